@@ -0,0 +1,93 @@
+// Package mysql measure the replication lag of mysql slaves, to be used with sqlt.WithLagProbe:
+//
+//	db, err := sqlt.Open("mysql", sources, sqlt.WithLagProbe(mysql.Probe{}))
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/albert-widi/sqlt"
+)
+
+// Error list
+var (
+	ErrNotReplica         = errors.New("Node is not a replica")
+	ErrReplicationStopped = errors.New("Replication is stopped")
+)
+
+// Probe measure the lag of the slave from Seconds_Behind_Source of the replica status,
+// SHOW SLAVE STATUS is used on servers older than 8.0.22
+type Probe struct{}
+
+var _ sqlt.LagProbe = Probe{}
+
+// Lag return the replication lag of the slave, ErrNotReplica when the node is writable or has
+// no replica status and ErrReplicationStopped when the replication threads are not running
+func (Probe) Lag(ctx context.Context, conn *sql.DB) (time.Duration, error) {
+	var readOnly bool
+	if err := conn.QueryRowContext(ctx, "SELECT @@read_only").Scan(&readOnly); err != nil {
+		return 0, err
+	}
+	if !readOnly {
+		return 0, ErrNotReplica
+	}
+
+	status, err := replicaStatus(ctx, conn, "SHOW REPLICA STATUS")
+	if err != nil {
+		if status, err = replicaStatus(ctx, conn, "SHOW SLAVE STATUS"); err != nil {
+			return 0, err
+		}
+	}
+	if status == nil {
+		return 0, ErrNotReplica
+	}
+
+	behind, ok := status["Seconds_Behind_Source"]
+	if !ok {
+		behind = status["Seconds_Behind_Master"]
+	}
+	if !behind.Valid {
+		return 0, ErrReplicationStopped
+	}
+	seconds, err := strconv.ParseInt(behind.String, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// replicaStatus return the columns of the first row of the status statement, nil when the
+// node has no replica status. The columns differ between versions so they are read by name
+func replicaStatus(ctx context.Context, conn *sql.DB, statement string) (map[string]sql.NullString, error) {
+	rows, err := conn.QueryContext(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	status := make(map[string]sql.NullString, len(columns))
+	for i, column := range columns {
+		status[column] = values[i]
+	}
+	return status, nil
+}
@@ -0,0 +1,135 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockDriver answer the statements of the scenario named by the DSN
+type mockDriver struct{}
+
+type (
+	mockConn   struct{ results map[string]mockResult }
+	mockResult struct {
+		columns []string
+		rows    [][]driver.Value
+		err     error
+	}
+	mockRows struct {
+		columns []string
+		rows    [][]driver.Value
+	}
+)
+
+var scenarios sync.Map
+
+func init() {
+	sql.Register("lagprobe-mysql-mock", mockDriver{})
+}
+
+func (mockDriver) Open(dsn string) (driver.Conn, error) {
+	results, _ := scenarios.Load(dsn)
+	return &mockConn{results: results.(map[string]mockResult)}, nil
+}
+
+func (*mockConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (*mockConn) Close() error                        { return nil }
+func (*mockConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (c *mockConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	r, ok := c.results[query]
+	if !ok {
+		return nil, errors.New("unexpected query " + query)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &mockRows{columns: r.columns, rows: r.rows}, nil
+}
+
+func (r *mockRows) Columns() []string { return r.columns }
+func (*mockRows) Close() error        { return nil }
+
+func (r *mockRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestProbe(t *testing.T) {
+	readOnly := mockResult{columns: []string{"@@read_only"}, rows: [][]driver.Value{{int64(1)}}}
+	tests := []struct {
+		name    string
+		results map[string]mockResult
+		want    time.Duration
+		wantErr error
+	}{
+		{
+			name: "replica status",
+			results: map[string]mockResult{
+				"SELECT @@read_only":  readOnly,
+				"SHOW REPLICA STATUS": {columns: []string{"Replica_IO_Running", "Seconds_Behind_Source"}, rows: [][]driver.Value{{"Yes", "3"}}},
+			},
+			want: 3 * time.Second,
+		},
+		{
+			name: "slave status of servers older than 8.0.22",
+			results: map[string]mockResult{
+				"SELECT @@read_only":  readOnly,
+				"SHOW REPLICA STATUS": {err: errors.New("You have an error in your SQL syntax")},
+				"SHOW SLAVE STATUS":   {columns: []string{"Slave_IO_Running", "Seconds_Behind_Master"}, rows: [][]driver.Value{{"Yes", "5"}}},
+			},
+			want: 5 * time.Second,
+		},
+		{
+			name: "replication stopped",
+			results: map[string]mockResult{
+				"SELECT @@read_only":  readOnly,
+				"SHOW REPLICA STATUS": {columns: []string{"Seconds_Behind_Source"}, rows: [][]driver.Value{{nil}}},
+			},
+			wantErr: ErrReplicationStopped,
+		},
+		{
+			name: "read only without replica status",
+			results: map[string]mockResult{
+				"SELECT @@read_only":  readOnly,
+				"SHOW REPLICA STATUS": {columns: []string{"Seconds_Behind_Source"}},
+			},
+			wantErr: ErrNotReplica,
+		},
+		{
+			name: "writable",
+			results: map[string]mockResult{
+				"SELECT @@read_only": {columns: []string{"@@read_only"}, rows: [][]driver.Value{{int64(0)}}},
+			},
+			wantErr: ErrNotReplica,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenarios.Store(tt.name, tt.results)
+			conn, err := sql.Open("lagprobe-mysql-mock", tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			lag, err := Probe{}.Lag(context.Background(), conn)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			if lag != tt.want {
+				t.Fatalf("lag %v, want %v", lag, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+// Package postgres measure the replication lag of postgres slaves, to be used with sqlt.WithLagProbe:
+//
+//	db, err := sqlt.Open("postgres", sources, sqlt.WithLagProbe(postgres.Probe{}))
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/albert-widi/sqlt"
+)
+
+// ErrNotReplica is returned when the node is not in recovery, e.g. it was promoted
+var ErrNotReplica = errors.New("Node is not a replica")
+
+// lagQuery return whether the node is in recovery and the seconds since the last replayed
+// transaction. A slave which replayed every received WAL is idle rather than behind, its lag is zero
+const lagQuery = `SELECT pg_is_in_recovery(),
+	CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END`
+
+// Probe measure the lag of the slave from its replay timestamp
+type Probe struct{}
+
+var _ sqlt.LagProbe = Probe{}
+
+// Lag return the replication lag of the slave, ErrNotReplica when the node is writable
+func (Probe) Lag(ctx context.Context, conn *sql.DB) (time.Duration, error) {
+	var (
+		recovery bool
+		seconds  float64
+	)
+	if err := conn.QueryRowContext(ctx, lagQuery).Scan(&recovery, &seconds); err != nil {
+		return 0, err
+	}
+	if !recovery {
+		return 0, ErrNotReplica
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
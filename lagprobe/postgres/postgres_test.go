@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockDriver answer the lag query with the row of the scenario named by the DSN
+type mockDriver struct{}
+
+type (
+	mockConn struct{ row []driver.Value }
+	mockRows struct {
+		row  []driver.Value
+		done bool
+	}
+)
+
+var scenarios sync.Map
+
+func init() {
+	sql.Register("lagprobe-postgres-mock", mockDriver{})
+}
+
+func (mockDriver) Open(dsn string) (driver.Conn, error) {
+	row, _ := scenarios.Load(dsn)
+	return &mockConn{row: row.([]driver.Value)}, nil
+}
+
+func (*mockConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (*mockConn) Close() error                        { return nil }
+func (*mockConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (c *mockConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if query != lagQuery {
+		return nil, errors.New("unexpected query " + query)
+	}
+	return &mockRows{row: c.row}, nil
+}
+
+func (*mockRows) Columns() []string { return []string{"pg_is_in_recovery", "lag"} }
+func (*mockRows) Close() error      { return nil }
+
+func (r *mockRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.row)
+	return nil
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name    string
+		row     []driver.Value
+		want    time.Duration
+		wantErr error
+	}{
+		{name: "behind", row: []driver.Value{true, 1.5}, want: 1500 * time.Millisecond},
+		{name: "caught up", row: []driver.Value{true, 0.0}},
+		{name: "promoted", row: []driver.Value{false, 0.0}, wantErr: ErrNotReplica},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenarios.Store(tt.name, tt.row)
+			conn, err := sql.Open("lagprobe-postgres-mock", tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			lag, err := Probe{}.Lag(context.Background(), conn)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			if lag != tt.want {
+				t.Fatalf("lag %v, want %v", lag, tt.want)
+			}
+		})
+	}
+}
@@ -10,206 +10,368 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
 )
 
 // Error list
 var (
 	ErrNoConnectionDetected = errors.New("No connection detected")
+	ErrNodeNotFound         = errors.New("Node not found")
+	ErrAllReplicasDown      = errors.New("All replicas are down")
+	ErrMasterUnavailable    = errors.New("Master is unavailable")
+	ErrNoMaster             = errors.New("No writable master")
+	ErrShutdown             = errors.New("Database is shutting down")
 )
 
 // DB struct wrapper for sqlx connection
 type DB struct {
-	sqlxdb     []*sqlx.DB
-	activedb   []int
-	inactivedb []int
+	// nodes is replaced as a whole when the nodes change, see nodeList
+	nodes      atomic.Pointer[[]*node]
 	driverName string
 	groupName  string
-	length     int
-	count      uint64
+	opts       options
+	balancer   balancer
+	// random is nil unless seeded by WithSelectionSeed
+	random *randSource
+	// route is swapped on every health change, mu serialize the changes
+	route atomic.Pointer[routing]
+	mu    sync.Mutex
+	// index of the node serving writes and the labels serving traffic, guarded by mu
+	masterIndex int
+	readLabel   string
+	writeLabel  string
+	// masterLost is set when election found no writable node, masterWait is closed when master is back
+	masterLost bool
+	masterWait chan struct{}
+	queued     atomic.Int64
+	// beating is the running heartbeat, managedBeat is set when a Manager drive the heartbeat
+	beating     atomic.Pointer[heartbeat]
+	managedBeat atomic.Bool
 	// for stats
-	stats     []DbStatus
-	heartBeat bool
-	stopBeat  chan bool
-	lastBeat  string
+	lastBeat string
+	// txRetry is the retry policy of transactions, replaced by SetTxRetryPolicy
+	txRetry atomic.Pointer[RetryPolicy]
+	report  *routingRecorder
+	// stmtCache is nil unless enabled by WithStmtCache
+	stmtCache *stmtCache
+	// cacheGen count the cache invalidations, cacheMu order them with the cache fills
+	cacheMu  sync.RWMutex
+	cacheGen atomic.Uint64
+	// cacheInvalidated is the last invalidation time of the cache tags, guarded by cacheMu
+	cacheInvalidated map[string]time.Time
+	cachePruneAt     int
+	// shutdown stop routing new queries, inflight count the routed queries
+	shutdown atomic.Bool
+	inflight atomic.Int64
+	// mapper and unsafe customize every node, guarded by mu
+	mapper *reflectx.Mapper
+	unsafe bool
+	// nextIndex is the index of the next added node, guarded by mu. Indexes are never reused
+	nextIndex int
+	// stopDiscovery is closed once to stop the replica discovery
+	stopDiscovery chan struct{}
+	discoveryOnce sync.Once
+	// shadowing is the number of queries mirrored to the shadow group
+	shadowing atomic.Int64
+	// lean is set when no option need the full pipeline, see runSingle
+	lean bool
+	// watchdog is nil unless enabled by WithWatchdog
+	watchdog *watchdog
+	// leaks is nil unless enabled by WithLeakDetector
+	leaks   *leakDetector
+	history *history
+	// queryStats is nil unless enabled by WithQueryStats
+	queryStats *queryStats
 }
 
 // DbStatus for status response
 type DbStatus struct {
-	Name       string      `json:"name"`
-	Connected  bool        `json:"connected"`
-	LastActive string      `json:"last_active"`
-	Error      interface{} `json:"error"`
+	Name          string            `json:"name"`
+	Group         string            `json:"group"`
+	Role          string            `json:"role"`
+	Weight        int               `json:"weight"`
+	Driver        string            `json:"driver"`
+	Label         string            `json:"label,omitempty"`
+	ServerVersion string            `json:"server_version,omitempty"`
+	Connected     bool              `json:"connected"`
+	Disabled      bool              `json:"disabled,omitempty"`
+	LastActive    string            `json:"last_active"`
+	Error         interface{}       `json:"error"`
+	Backend       string            `json:"backend,omitempty"`
+	Queries       uint64            `json:"queries"`
+	Errors        uint64            `json:"errors"`
+	Inflight      int64             `json:"inflight"`
+	AvgLatency    time.Duration     `json:"avg_latency_ns"`
+	P95Latency    time.Duration     `json:"p95_latency_ns"`
+	LastErrorAt   string            `json:"last_error_at,omitempty"`
+	NextProbeAt   string            `json:"next_probe_at,omitempty"`
+	Lag           *time.Duration    `json:"lag_ns,omitempty"`
+	Host          string            `json:"host,omitempty"`
+	Port          string            `json:"port,omitempty"`
+	Database      string            `json:"database,omitempty"`
+	Tags          map[string]string `json:"tags,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+}
+
+// Labels return the node identity, tags and metadata as a flat map suitable for metrics labels
+func (s DbStatus) Labels() map[string]string {
+	labels := make(map[string]string, len(s.Tags)+len(s.Metadata)+4)
+	for k, v := range s.Metadata {
+		labels[k] = v
+	}
+	for k, v := range s.Tags {
+		labels[k] = v
+	}
+	labels["name"] = s.Name
+	labels["group"] = s.Group
+	labels["role"] = s.Role
+	labels["driver"] = s.Driver
+	if s.Label != "" {
+		labels["label"] = s.Label
+	}
+	return labels
 }
 
 type statusResponse struct {
-	Dbs       interface{} `json:"db_list"`
-	Heartbeat bool        `json:"heartbeat"`
-	Lastbeat  string      `json:"last_beat"`
+	Dbs       interface{}            `json:"db_list"`
+	Heartbeat bool                   `json:"heartbeat"`
+	Lastbeat  string                 `json:"last_beat"`
+	Pool      map[string]sql.DBStats `json:"pool"`
+	History   []NodeEvent            `json:"history,omitempty"`
 }
 
 const defaultGroupName = "sqlt_open"
 
-var dbLengthMutex = &sync.Mutex{}
+func openConnection(driverName, sources string, groupName string, opts []Option) (*DB, error) {
+	return openContextConnection(context.Background(), driverName, sources, groupName, opts)
+}
 
-func openConnection(driverName, sources string, groupName string) (*DB, error) {
-	db, err := open(context.Background(), driverName, sources, groupName)
+// Connect open the connection without failing on unreachable nodes, they are marked inactive
+// and brought back by the heartbeat once they can be reached. Only invalid sources return error
+func Connect(driverName, sources string, opts ...Option) (*DB, error) {
+	return ConnectContext(context.Background(), driverName, sources, opts...)
+}
+
+// ConnectContext is Connect with context
+func ConnectContext(ctx context.Context, driverName, sources string, opts ...Option) (*DB, error) {
+	db, err := open(ctx, driverName, sources, "", opts)
 	if err != nil {
 		return nil, err
 	}
-	return db, db.Ping()
+	// ping only apply the health of every node, unreachable nodes are not an error here
+	db.PingContext(ctx)
+	db.startDiscovery()
+	return db, nil
+}
+
+// newDB create an empty DB, nodes are added by the caller
+func newDB(driverName string, opts options) *DB {
+	random := newRandSource(opts.seed)
+	db := &DB{
+		driverName:    driverName,
+		opts:          opts,
+		random:        random,
+		balancer:      opts.balancer(random),
+		report:        newRoutingRecorder(),
+		stopDiscovery: make(chan struct{}),
+		lean:          !opts.pipeline,
+		watchdog:      newWatchdog(opts.watchdog),
+		leaks:         newLeakDetector(opts.leakDetector, opts.logger),
+		history:       newHistory(opts.historySize),
+		queryStats:    newQueryStats(opts.queryStats),
+	}
+	db.txRetry.Store(&opts.writeRetry)
+	return db
 }
 
 // Open connection to database
-func Open(driverName, sources string) (*DB, error) {
-	return openConnection(driverName, sources, "")
+func Open(driverName, sources string, opts ...Option) (*DB, error) {
+	return openConnection(driverName, sources, "", opts)
 }
 
 // OpenWithName open the connection and set connection group name
-func OpenWithName(driverName, sources string, name string) (*DB, error) {
-	return openConnection(driverName, sources, name)
+func OpenWithName(driverName, sources string, name string, opts ...Option) (*DB, error) {
+	return openConnection(driverName, sources, name, opts)
+}
+
+// MustOpen (panic) open the connection to database
+func MustOpen(driverName, sources string, opts ...Option) *DB {
+	return must(Open(driverName, sources, opts...))
+}
+
+// MustOpenWithContext (panic) open the connection to database with context
+func MustOpenWithContext(ctx context.Context, driverName, sources string, opts ...Option) *DB {
+	return must(OpenWithContext(ctx, driverName, sources, opts...))
+}
+
+// MustConnect (panic) open the connection without failing on unreachable nodes, see Connect
+func MustConnect(driverName, sources string, opts ...Option) *DB {
+	return must(Connect(driverName, sources, opts...))
+}
+
+func must(db *DB, err error) *DB {
+	if err != nil {
+		panic(err)
+	}
+	return db
 }
 
 // GetStatus return database status
 func (db *DB) GetStatus() ([]DbStatus, error) {
-	if len(db.stats) == 0 {
-		return db.stats, ErrNoConnectionDetected
+	if len(db.nodeList()) == 0 {
+		return []DbStatus{}, ErrNoConnectionDetected
 	}
 
 	// if heartbeat is not enabled, ping to get status before send status
-	if !db.heartBeat {
+	if !db.heartbeatRunning() {
 		db.Ping()
 	}
-	return db.stats, nil
+	return db.status(), nil
+}
+
+// status return a copy of every node status
+func (db *DB) status() []DbStatus {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	master := db.master()
+	nodes := db.members()
+	stats := make([]DbStatus, len(nodes))
+	for i, n := range nodes {
+		stat := n.status
+		stat.Group = db.groupName
+		stat.Role = "slave"
+		if n.index == master {
+			stat.Role = "master"
+		}
+		stat.Weight = n.weight
+		stat.Driver = n.driver
+		stat.Host, stat.Port, stat.Database = n.info.Host, n.info.Port, n.info.Database
+		stat.Label = n.label
+		stat.Disabled = n.disabled
+		stat.Queries = n.queries.Load()
+		stat.Errors = n.errors.Load()
+		stat.Inflight = n.inflight.Load()
+		stat.AvgLatency, stat.P95Latency = n.window.summary()
+		if at := n.lastError.Load(); at > 0 {
+			stat.LastErrorAt = time.Unix(0, at).Format(time.RFC1123)
+		}
+		if at := n.nextProbe.Load(); at > 0 {
+			stat.NextProbeAt = time.Unix(0, at).Format(time.RFC1123)
+		}
+		if lag := n.lag.Load(); lag >= 0 {
+			stat.Lag = new(time.Duration)
+			*stat.Lag = time.Duration(lag)
+		}
+		stat.Tags = copyLabels(n.tags)
+		stat.Metadata = copyLabels(n.metadata)
+		stats[i] = stat
+	}
+	return stats
 }
 
-// DoHeartBeat will automatically spawn a goroutines to ping your database every one second, use this carefully
-func (db *DB) DoHeartBeat() {
-	if !db.heartBeat {
-		ticker := time.NewTicker(time.Second * 2)
-		db.stopBeat = make(chan bool)
-		go func() {
-			for {
-				select {
-				case <-ticker.C:
-					db.Ping()
-					db.lastBeat = time.Now().Format(time.RFC1123)
-				case <-db.stopBeat:
-					return
-				}
-			}
-		}()
-	}
-	db.heartBeat = true
+// HeartbeatOptions configure StartHeartbeat
+type HeartbeatOptions struct {
+	// Interval between ticks, default to the shortest ping interval of the nodes.
+	// Every node is still pinged on its own interval
+	Interval time.Duration
 }
 
-// StopBeat will stop heartbeat, exit from goroutines
-func (db *DB) StopBeat() {
-	if !db.heartBeat {
-		return
-	}
-	db.stopBeat <- true
+// heartbeat is a running heartbeat goroutine
+type heartbeat struct {
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
-// Ping database
-func (db *DB) Ping() error {
-	var err error
+// stop the heartbeat and wait for its goroutine to exit
+func (hb *heartbeat) stop() {
+	hb.cancel()
+	<-hb.done
+}
 
-	if !db.heartBeat {
-		for _, val := range db.sqlxdb {
-			err = val.Ping()
-			if err != nil {
-				return err
-			}
-		}
-		return err
+// StartHeartbeat ping the nodes in the background until ctx is done or the returned stop is called.
+// A running heartbeat is stopped first, so the heartbeat can be restarted with new options.
+// stop wait for the heartbeat to exit and is safe to call more than once
+func (db *DB) StartHeartbeat(ctx context.Context, opts HeartbeatOptions) (stop func()) {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = db.beatInterval()
 	}
 
-	for i := 0; i < len(db.activedb); i++ {
-		val := db.activedb[i]
-		err = db.sqlxdb[val].Ping()
-		name := db.stats[val].Name
-
-		if err != nil {
-			if db.length <= 1 {
-				return err
-			}
-
-			db.stats[val].Connected = false
-			db.activedb = append(db.activedb[:i], db.activedb[i+1:]...)
-			i--
-			db.inactivedb = append(db.inactivedb, val)
-			db.stats[val].Error = errors.New(name + ": " + err.Error())
-			dbLengthMutex.Lock()
-			db.length--
-			dbLengthMutex.Unlock()
-		} else {
-			db.stats[val].Connected = true
-			db.stats[val].LastActive = time.Now().Format(time.RFC1123)
-			db.stats[val].Error = nil
-		}
+	ctx, cancel := context.WithCancel(ctx)
+	hb := &heartbeat{cancel: cancel, done: make(chan struct{})}
+	if old := db.beating.Swap(hb); old != nil {
+		old.stop()
 	}
 
-	for i := 0; i < len(db.inactivedb); i++ {
-		val := db.inactivedb[i]
-		err = db.sqlxdb[val].Ping()
-		name := db.stats[val].Name
-
-		if err != nil {
-			db.stats[val].Connected = false
-			db.stats[val].Error = errors.New(name + ": " + err.Error())
-		} else {
-			db.stats[val].Connected = true
-			db.inactivedb = append(db.inactivedb[:i], db.inactivedb[i+1:]...)
-			i--
-			db.activedb = append(db.activedb, val)
-			db.stats[val].LastActive = time.Now().Format(time.RFC1123)
-			db.stats[val].Error = nil
-			dbLengthMutex.Lock()
-			db.length++
-			dbLengthMutex.Unlock()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				db.beat(ctx, now)
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
+	go func() {
+		defer wg.Done()
+		db.probe(ctx, interval)
+	}()
+	go func() {
+		wg.Wait()
+		db.beating.CompareAndSwap(hb, nil)
+		close(hb.done)
+	}()
+	return hb.stop
+}
+
+// DoHeartBeat will automatically spawn a goroutines to ping your database, use this carefully.
+// It does nothing when the heartbeat is already running or driven by a Manager
+func (db *DB) DoHeartBeat() {
+	if db.managedBeat.Load() || db.beating.Load() != nil {
+		return
 	}
-	return err
+	db.StartHeartbeat(context.Background(), HeartbeatOptions{})
 }
 
-// Prepare return sql stmt
-func (db *DB) Prepare(query string) (*Stmt, error) {
-	var err error
-	stmt := new(Stmt)
-	stmts := make([]*sql.Stmt, len(db.sqlxdb))
-
-	for i := range db.sqlxdb {
-		stmts[i], err = db.sqlxdb[i].Prepare(query)
+// beat ping the nodes due at now
+func (db *DB) beat(ctx context.Context, now time.Time) {
+	db.ping(ctx, db.dueNodes(now))
+	db.mu.Lock()
+	db.lastBeat = time.Now().Format(time.RFC1123)
+	db.mu.Unlock()
+}
 
-		if err != nil {
-			return nil, err
-		}
+// StopBeat will stop heartbeat, exit from goroutines
+func (db *DB) StopBeat() {
+	if hb := db.beating.Load(); hb != nil {
+		hb.stop()
 	}
-	stmt.db = db
-	stmt.stmts = stmts
-	return stmt, nil
 }
 
-// Preparex sqlx stmt
-func (db *DB) Preparex(query string) (*Stmtx, error) {
-	var err error
-	stmts := make([]*sqlx.Stmt, len(db.sqlxdb))
-
-	for i := range db.sqlxdb {
-		stmts[i], err = db.sqlxdb[i].Preparex(query)
-
-		if err != nil {
-			return nil, err
-		}
-	}
+// heartbeatRunning report whether the nodes are pinged in the background
+func (db *DB) heartbeatRunning() bool {
+	return db.managedBeat.Load() || db.beating.Load() != nil
+}
 
-	return &Stmtx{db: db, stmts: stmts}, nil
+// Ping database
+func (db *DB) Ping() error {
+	return db.PingContext(context.Background())
 }
 
 // SetMaxOpenConnections to set max connections
 func (db *DB) SetMaxOpenConnections(max int) {
-	for i := range db.sqlxdb {
-		db.sqlxdb[i].SetMaxOpenConns(max)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, n := range db.nodeList() {
+		n.pool.maxOpen = max
+		n.db().SetMaxOpenConns(max)
 	}
 }
 
@@ -217,294 +379,258 @@ func (db *DB) SetMaxOpenConnections(max int) {
 // Expired connections may be closed lazily before reuse.
 // If d <= 0, connections are reused forever.
 func (db *DB) SetConnMaxLifetime(d time.Duration) {
-	for i := range db.sqlxdb {
-		db.sqlxdb[i].SetConnMaxLifetime(d)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, n := range db.nodeList() {
+		n.pool.maxLifetime = d
+		setConnMaxLifetime(n.db(), d)
+	}
+}
+
+// SetConnMaxIdleTime sets the maximum amount of time a connection may be idle.
+// If d <= 0, connections are not closed due to a connection's idle time.
+func (db *DB) SetConnMaxIdleTime(d time.Duration) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, n := range db.nodeList() {
+		n.pool.maxIdleTime = d
+		n.db().SetConnMaxIdleTime(d)
 	}
 }
 
 // Slave return slave database
 func (db *DB) Slave() *sqlx.DB {
-	return db.sqlxdb[db.slave()]
+	return db.slave().db()
 }
 
 // Master return master database
 func (db *DB) Master() *sqlx.DB {
-	return db.sqlxdb[0]
+	return db.nodeAt(db.master()).db()
+}
+
+// DriverName return the driver name of the DB
+func (db *DB) DriverName() string {
+	return db.driverName
+}
+
+// GroupName return the connection group name
+func (db *DB) GroupName() string {
+	return db.groupName
+}
+
+// NodeNames return the name of every node in the configured order
+func (db *DB) NodeNames() []string {
+	nodes := db.members()
+	names := make([]string, len(nodes))
+	for i, n := range nodes {
+		names[i] = n.name
+	}
+	return names
+}
+
+// NumActiveSlaves return the number of slaves serving reads
+func (db *DB) NumActiveSlaves() int {
+	return len(db.route.Load().slaves)
+}
+
+// IsHealthy report whether the last ping of the named node succeeded
+func (db *DB) IsHealthy(name string) bool {
+	n, err := db.node(name)
+	if err != nil {
+		return false
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return n.status.Connected
 }
 
 // Query queries the database and returns an *sql.Rows.
 func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	r, err := db.sqlxdb[db.slave()].Query(query, args...)
-	return r, err
+	return db.QueryContext(context.Background(), query, args...)
 }
 
 // QueryRow queries the database and returns an *sqlx.Row.
 func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
-	rows := db.sqlxdb[db.slave()].QueryRow(query, args...)
-	return rows
+	return db.QueryRowContext(context.Background(), query, args...)
 }
 
 // Queryx queries the database and returns an *sqlx.Rows.
 func (db *DB) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
-	r, err := db.sqlxdb[db.slave()].Queryx(query, args...)
-	return r, err
+	return db.QueryxContext(context.Background(), query, args...)
+}
+
+// QueryxMaster queries master and returns an *sqlx.Rows.
+func (db *DB) QueryxMaster(query string, args ...interface{}) (*sqlx.Rows, error) {
+	return db.QueryxMasterContext(context.Background(), query, args...)
 }
 
 // QueryRowx queries the database and returns an *sqlx.Row.
 func (db *DB) QueryRowx(query string, args ...interface{}) *sqlx.Row {
-	rows := db.sqlxdb[db.slave()].QueryRowx(query, args...)
-	return rows
+	return db.QueryRowxContext(context.Background(), query, args...)
 }
 
 // Exec using master db
 func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
-	return db.sqlxdb[0].Exec(query, args...)
+	return db.ExecContext(context.Background(), query, args...)
 }
 
 // MustExec (panic) runs MustExec using master database.
 func (db *DB) MustExec(query string, args ...interface{}) sql.Result {
-	return db.sqlxdb[0].MustExec(query, args...)
+	return db.MustExecContext(context.Background(), query, args...)
 }
 
 // Select using slave db.
 func (db *DB) Select(dest interface{}, query string, args ...interface{}) error {
-	return db.sqlxdb[db.slave()].Select(dest, query, args...)
+	return db.SelectContext(context.Background(), dest, query, args...)
 }
 
 // SelectMaster using master db.
 func (db *DB) SelectMaster(dest interface{}, query string, args ...interface{}) error {
-	return db.sqlxdb[0].Select(dest, query, args...)
+	return db.SelectMasterContext(context.Background(), dest, query, args...)
 }
 
 // Get using slave.
 func (db *DB) Get(dest interface{}, query string, args ...interface{}) error {
-	return db.sqlxdb[db.slave()].Get(dest, query, args...)
+	return db.GetContext(context.Background(), dest, query, args...)
 }
 
 // GetMaster using master.
 func (db *DB) GetMaster(dest interface{}, query string, args ...interface{}) error {
-	return db.sqlxdb[0].Get(dest, query, args...)
+	return db.GetMasterContext(context.Background(), dest, query, args...)
 }
 
 // NamedExec using master db.
 func (db *DB) NamedExec(query string, arg interface{}) (sql.Result, error) {
-	return db.sqlxdb[0].NamedExec(query, arg)
+	return db.NamedExecContext(context.Background(), query, arg)
 }
 
 // Begin sql transaction
 func (db *DB) Begin() (*sql.Tx, error) {
-	return db.sqlxdb[0].Begin()
+	return db.Master().Begin()
 }
 
-// Beginx sqlx transaction
-func (db *DB) Beginx() (*sqlx.Tx, error) {
-	return db.sqlxdb[0].Beginx()
+// Beginx sqlx transaction wrapped with the query helpers of DB
+func (db *DB) Beginx() (*Tx, error) {
+	return db.BeginTxx(context.Background(), nil)
 }
 
-// MustBegin starts a transaction, and panics on error. Returns an *sqlx.Tx instead
+// MustBegin starts a transaction, and panics on error. Returns a *Tx instead
 // of an *sql.Tx.
-func (db *DB) MustBegin() *sqlx.Tx {
-	tx, err := db.sqlxdb[0].Beginx()
+func (db *DB) MustBegin() *Tx {
+	tx, err := db.Beginx()
 	if err != nil {
 		panic(err)
 	}
 	return tx
 }
 
-// Rebind query
+// Rebind query for the driver of the group, the query is kept with ? bindvars when the nodes
+// use different drivers and is rebound to the node serving it instead
 func (db *DB) Rebind(query string) string {
-	return db.sqlxdb[db.slave()].Rebind(query)
+	if db.mixedBind() {
+		return query
+	}
+	return sqlx.Rebind(db.BindType(), query)
 }
 
 // RebindMaster will rebind query for master
 func (db *DB) RebindMaster(query string) string {
-	return db.sqlxdb[0].Rebind(query)
+	if db.mixedBind() {
+		return query
+	}
+	return db.nodeAt(db.master()).rebind(query)
+}
+
+// BindType return the bindvar type of the group driver, e.g. sqlx.DOLLAR for postgres
+func (db *DB) BindType() int {
+	if bind := sqlx.BindType(db.driverName); bind != sqlx.UNKNOWN {
+		return bind
+	}
+	// driver names unknown to sqlx use the bindvar of their dialect
+	for _, n := range db.members() {
+		if n.driver == db.driverName {
+			return n.bind
+		}
+	}
+	return sqlx.UNKNOWN
 }
 
 // Close closes all database connections
 func (db *DB) Close() error {
-	for _, val := range db.sqlxdb {
-		err := val.Close()
-		if err != nil {
-			return err
+	db.discoveryOnce.Do(func() {
+		if db.stopDiscovery != nil {
+			close(db.stopDiscovery)
 		}
+	})
+	if db.stmtCache != nil {
+		db.stmtCache.close()
 	}
-	return nil
+	var errs []error
+	for _, n := range db.nodeList() {
+		if err := n.db().Close(); err != nil {
+			errs = append(errs, wrapError(err, n, "Close", ""))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // SetMaxIdleConns sets the maximum number of connections in the idle
 // connection pool for all connections
 func (db *DB) SetMaxIdleConns(n int) {
-	for _, val := range db.sqlxdb {
-		val.SetMaxIdleConns(n)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, val := range db.nodeList() {
+		val.pool.maxIdle = &n
+		val.db().SetMaxIdleConns(n)
 	}
 }
 
-// Stmt implement sql stmt
-type Stmt struct {
-	db    *DB
-	stmts []*sql.Stmt
-}
-
-// Exec will always go to production
-func (st *Stmt) Exec(args ...interface{}) (sql.Result, error) {
-	return st.stmts[0].Exec(args...)
-}
-
-// Query will always go to slave
-func (st *Stmt) Query(args ...interface{}) (*sql.Rows, error) {
-	return st.stmts[st.db.slave()].Query(args...)
-}
-
-// QueryMaster will use master db
-func (st *Stmt) QueryMaster(args ...interface{}) (*sql.Rows, error) {
-	return st.stmts[0].Query(args...)
-}
-
-// QueryRow will always go to slave
-func (st *Stmt) QueryRow(args ...interface{}) *sql.Row {
-	return st.stmts[st.db.slave()].QueryRow(args...)
-}
-
-// QueryRowMaster will use master db
-func (st *Stmt) QueryRowMaster(args ...interface{}) *sql.Row {
-	return st.stmts[0].QueryRow(args...)
-}
-
-// Close stmt
-func (st *Stmt) Close() error {
-	for i := range st.stmts {
-		err := st.stmts[i].Close()
-
-		if err != nil {
-			return err
-		}
+// InitMocking initialize the dbconnection mocking
+func InitMocking(dbConn *sql.DB, slaveAmount int) *DB {
+	slaves := make([]*sql.DB, slaveAmount)
+	for i := range slaves {
+		slaves[i] = dbConn
 	}
-	return nil
-}
-
-// Stmtx implement sqlx stmt
-type Stmtx struct {
-	db    *DB
-	stmts []*sqlx.Stmt
+	return InitMockingWithConns(dbConn, slaves...)
 }
 
-// Close all dbs connection
-func (st *Stmtx) Close() error {
-	for i := range st.stmts {
-		err := st.stmts[i].Close()
-
-		if err != nil {
-			return err
-		}
+// InitMockingWithConns initialize the mocking with its own connection for every node,
+// e.g. a separate sqlmock for master and each slave to assert where the query is routed.
+// The driver is detected from the master connection, postgres when unknown
+func InitMockingWithConns(master *sql.DB, slaves ...*sql.DB) *DB {
+	driverName := "postgres"
+	if d := detectDialect("", master.Driver()); d != DialectUnknown {
+		driverName = string(d)
 	}
-	return nil
-}
-
-// Exec will always go to production
-func (st *Stmtx) Exec(args ...interface{}) (sql.Result, error) {
-	return st.stmts[0].Exec(args...)
-
-}
-
-// Query will always go to slave
-func (st *Stmtx) Query(args ...interface{}) (*sql.Rows, error) {
-	return st.stmts[st.db.slave()].Query(args...)
-}
-
-// QueryMaster will use master db
-func (st *Stmtx) QueryMaster(args ...interface{}) (*sql.Rows, error) {
-	return st.stmts[0].Query(args...)
-}
-
-// QueryRow will always go to slave
-func (st *Stmtx) QueryRow(args ...interface{}) *sql.Row {
-	return st.stmts[st.db.slave()].QueryRow(args...)
-}
-
-// QueryRowMaster will use master db
-func (st *Stmtx) QueryRowMaster(args ...interface{}) *sql.Row {
-	return st.stmts[0].QueryRow(args...)
-}
-
-// MustExec using master database
-func (st *Stmtx) MustExec(args ...interface{}) sql.Result {
-	return st.stmts[0].MustExec(args...)
-}
-
-// Queryx will always go to slave
-func (st *Stmtx) Queryx(args ...interface{}) (*sqlx.Rows, error) {
-	return st.stmts[st.db.slave()].Queryx(args...)
-}
-
-// QueryRowx will always go to slave
-func (st *Stmtx) QueryRowx(args ...interface{}) *sqlx.Row {
-	return st.stmts[st.db.slave()].QueryRowx(args...)
-}
-
-// QueryRowxMaster will always go to master
-func (st *Stmtx) QueryRowxMaster(args ...interface{}) *sqlx.Row {
-	return st.stmts[0].QueryRowx(args...)
+	return InitMockingWithDriver(driverName, master, slaves...)
 }
 
-// Get will always go to slave
-func (st *Stmtx) Get(dest interface{}, args ...interface{}) error {
-	return st.stmts[st.db.slave()].Get(dest, args...)
+// InitMockingWithDriver is InitMockingWithConns using driverName for rebind and driver specific queries
+func InitMockingWithDriver(driverName string, master *sql.DB, slaves ...*sql.DB) *DB {
+	return initMocking(driverName, newOptions(nil), master, slaves...)
 }
 
-// GetMaster will always go to master
-func (st *Stmtx) GetMaster(dest interface{}, args ...interface{}) error {
-	return st.stmts[0].Get(dest, args...)
-}
-
-// Select will always go to slave
-func (st *Stmtx) Select(dest interface{}, args ...interface{}) error {
-	return st.stmts[st.db.slave()].Select(dest, args...)
-}
-
-// SelectMaster will always go to master
-func (st *Stmtx) SelectMaster(dest interface{}, args ...interface{}) error {
-	return st.stmts[0].Select(dest, args...)
-}
-
-// slave
-func (db *DB) slave() int {
-	dbLengthMutex.Lock()
-	defer dbLengthMutex.Unlock()
-	if db.length <= 1 {
-		return 0
-	}
-
-	slave := int(1 + (atomic.AddUint64(&db.count, 1) % uint64(db.length-1)))
-	active := db.activedb[slave]
-	return active
-}
-
-//InitMocking initialize the dbconnection mocking
-func InitMocking(dbConn *sql.DB, slaveAmount int) *DB {
-
-	db := &DB{
-		sqlxdb: make([]*sqlx.DB, slaveAmount+1),
-		stats:  make([]DbStatus, slaveAmount+1),
-	}
-
-	for i := 0; i <= slaveAmount; i++ {
-		db.sqlxdb[i] = sqlx.NewDb(dbConn, "postgres")
+// initMocking initialize the mocking with opts, the nodes are named master and slave-1, slave-2...
+func initMocking(driverName string, opts options, master *sql.DB, slaves ...*sql.DB) *DB {
+	db := newDB(driverName, opts)
+	for i, conn := range append([]*sql.DB{master}, slaves...) {
 		name := fmt.Sprintf("slave-%d", i)
 		if i == 0 {
 			name = "master"
 		}
-
-		db.stats[i] = DbStatus{
-			Name:       name,
-			Connected:  true,
-			LastActive: time.Now().String(),
+		n := newNode(i, name, sqlx.NewDb(conn, driverName))
+		n.setDriver(driverName)
+		if interval, ok := opts.pingIntervals[name]; ok {
+			n.pingInterval = interval
 		}
-		db.activedb = append(db.activedb, i)
+		db.applyLimit(n)
+		db.appendNode(n)
 	}
 
-	db.driverName = "postgres"
 	db.groupName = "sqlt-open"
-	db.length = len(db.sqlxdb)
+	if opts.stmtCacheSize > 0 && !opts.queryComments {
+		db.stmtCache = newStmtCache(opts.stmtCacheSize, len(db.nodeList()))
+	}
+	db.updateRouting()
 	return db
 }
@@ -0,0 +1,126 @@
+package sqlt
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// balancer pick the slave serving the next read
+type balancer interface {
+	// pick is only called when the routing has at least one slave
+	pick(r *routing) *node
+	// observe is called after every query with its latency
+	observe(n *node, d time.Duration)
+}
+
+// WithSelectionSeed make the random choices of the routing reproducible, e.g. the latency balancer,
+// hedged reads and master reads. The latencies compared by the latency balancer are still measured,
+// so its picks only repeat for the same latencies. Round robin always start from the first slave
+func WithSelectionSeed(seed uint64) Option {
+	return func(o *options) {
+		o.seed = &seed
+	}
+}
+
+// randSource is the source of the random choices of the DB, nil use the global source
+type randSource struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newRandSource(seed *uint64) *randSource {
+	if seed == nil {
+		return nil
+	}
+	return &randSource{r: rand.New(rand.NewPCG(*seed, *seed))}
+}
+
+func (s *randSource) IntN(n int) int {
+	if s == nil {
+		return rand.IntN(n)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.IntN(n)
+}
+
+func (s *randSource) Float64() float64 {
+	if s == nil {
+		return rand.Float64()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Float64()
+}
+
+// roundRobin is the default balancer, slaves are picked in turn based on their weight
+type roundRobin struct {
+	count atomic.Uint64
+}
+
+func (rr *roundRobin) pick(r *routing) *node {
+	n := rr.count.Add(1) % r.total
+	for i, cum := range r.cumulative {
+		if n < cum {
+			return r.slaves[i]
+		}
+	}
+	return r.slaves[0]
+}
+
+func (rr *roundRobin) observe(n *node, d time.Duration) {}
+
+const defaultLatencyDecay = 0.3
+
+// latencyExplore is the share of picks made at random, so a node which lost every comparison
+// after a slow sample keeps serving reads and its latency keeps being measured
+const latencyExplore = 0.05
+
+// latencyBalancer compare two random slaves and pick the one with lower latency
+// relative to its weight, known as power of two choices
+type latencyBalancer struct {
+	decay  float64
+	random *randSource
+}
+
+func (lb *latencyBalancer) pick(r *routing) *node {
+	if len(r.slaves) == 1 {
+		return r.slaves[0]
+	}
+
+	i := lb.random.IntN(len(r.slaves))
+	if lb.random.Float64() < latencyExplore {
+		return r.slaves[i]
+	}
+	j := lb.random.IntN(len(r.slaves) - 1)
+	if j >= i {
+		j++
+	}
+
+	// node without latency sample yet is always preferred so it gets measured
+	if lb.score(r, i) <= lb.score(r, j) {
+		return r.slaves[i]
+	}
+	return r.slaves[j]
+}
+
+func (lb *latencyBalancer) score(r *routing, i int) float64 {
+	return math.Float64frombits(r.slaves[i].latency.Load()) / float64(r.weights[i])
+}
+
+func (lb *latencyBalancer) observe(n *node, d time.Duration) {
+	for {
+		old := n.latency.Load()
+		avg := math.Float64frombits(old)
+		next := float64(d)
+		if avg > 0 {
+			next = avg + lb.decay*(float64(d)-avg)
+		}
+		if n.latency.CompareAndSwap(old, math.Float64bits(next)) {
+			return
+		}
+	}
+}
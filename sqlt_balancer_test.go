@@ -0,0 +1,85 @@
+package sqlt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoundRobinWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]int
+		reads   int
+		want    map[string]int
+	}{
+		{
+			name:  "equal weights",
+			reads: 30,
+			want:  map[string]int{"slave-1": 10, "slave-2": 10, "slave-3": 10},
+		},
+		{
+			name:    "weighted slave",
+			weights: map[string]int{"slave-2": 3},
+			reads:   50,
+			want:    map[string]int{"slave-1": 10, "slave-2": 30, "slave-3": 10},
+		},
+		{
+			name:    "every slave weighted",
+			weights: map[string]int{"slave-1": 2, "slave-2": 1, "slave-3": 4},
+			reads:   70,
+			want:    map[string]int{"slave-1": 20, "slave-2": 10, "slave-3": 40},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDB(t, 3)
+			for name, weight := range tt.weights {
+				if err := db.SetWeight(name, weight); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := make(map[string]int)
+			for i := 0; i < tt.reads; i++ {
+				got[readNode(t, db)]++
+			}
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Fatalf("reads %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLatencyBalancer(t *testing.T) {
+	tests := []struct {
+		name string
+		// delay of every query of the node
+		delays map[string]time.Duration
+		fast   string
+	}{
+		{name: "first slave slow", delays: map[string]time.Duration{"slave-1": 5 * time.Millisecond}, fast: "slave-2"},
+		{name: "second slave slow", delays: map[string]time.Duration{"slave-2": 5 * time.Millisecond}, fast: "slave-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDB(t, 2, WithLatencyBalancer(0.5), WithSelectionSeed(1))
+			for name, d := range tt.delays {
+				if err := db.DelayNode(name, d); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			const reads = 100
+			got := make(map[string]int)
+			for i := 0; i < reads; i++ {
+				got[readNode(t, db)]++
+			}
+			// the slow slave keeps a share of the reads to measure its latency
+			if got[tt.fast] < reads*8/10 {
+				t.Fatalf("reads %v, want most reads on %s", got, tt.fast)
+			}
+		})
+	}
+}
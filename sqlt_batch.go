@@ -0,0 +1,97 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Batch queue statements executed together on master, see DB.Batch
+type Batch struct {
+	db    *DB
+	ctx   context.Context
+	items []batchItem
+}
+
+type batchItem struct {
+	query string
+	args  []interface{}
+}
+
+// BatchError is returned when a statement of the batch fail, the whole batch is rolled back
+type BatchError struct {
+	// Index of the failed statement in the queue order
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("sqlt: batch statement %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Batch return an empty batch, queued statements are executed in a single transaction on master
+// so the batch pay a single commit instead of one per statement
+func (db *DB) Batch(ctx context.Context) *Batch {
+	return &Batch{db: db, ctx: ctx}
+}
+
+// Queue add the statement to the batch
+func (b *Batch) Queue(query string, args ...interface{}) *Batch {
+	b.items = append(b.items, batchItem{query: query, args: args})
+	return b
+}
+
+// Len return the number of queued statements
+func (b *Batch) Len() int {
+	return len(b.items)
+}
+
+// Exec execute the queued statements in order and return the result of every statement.
+// The batch is retried following WithWriteRetry, queued statements are kept so it can be executed again
+func (b *Batch) Exec() ([]sql.Result, error) {
+	if len(b.items) == 0 {
+		return nil, nil
+	}
+
+	queries := make([]string, len(b.items))
+	for i, item := range b.items {
+		queries[i] = item.query
+	}
+	c := call{op: "Batch", query: strings.Join(queries, ";\n"), write: true}
+
+	var results []sql.Result
+	err := b.db.retry(b.ctx, b.db.opts.writeRetry, func() error {
+		return b.db.run(b.ctx, c, func(ctx context.Context, n *node, _ string) error {
+			var err error
+			results, err = b.exec(ctx, n)
+			return err
+		})
+	})
+	return results, err
+}
+
+// exec run the statements in a transaction on the node
+func (b *Batch) exec(ctx context.Context, n *node) ([]sql.Result, error) {
+	tx, err := n.db().BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]sql.Result, len(b.items))
+	for i, item := range b.items {
+		query := b.db.comment(ctx, n, b.db.rebind(n, item.query))
+		if results[i], err = tx.ExecContext(ctx, query, item.args...); err != nil {
+			tx.Rollback()
+			return nil, &BatchError{Index: i, Err: err}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
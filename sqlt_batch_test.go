@@ -0,0 +1,59 @@
+package sqlt
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		queries []string
+		want    []string
+		// failed is the index of the failed statement, -1 when the batch succeed
+		failed int
+	}{
+		{name: "empty", failed: -1},
+		{
+			name:    "committed",
+			queries: []string{"UPDATE stock SET quantity = 0", "DELETE FROM orders"},
+			want:    []string{"BEGIN", "UPDATE stock SET quantity = 0", "DELETE FROM orders", "COMMIT"},
+			failed:  -1,
+		},
+		{
+			name:    "rolled back",
+			queries: []string{"UPDATE stock SET quantity = 0", "UPDATE fail", "DELETE FROM orders"},
+			want:    []string{"BEGIN", "UPDATE stock SET quantity = 0", "UPDATE fail", "ROLLBACK"},
+			failed:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			master := t.Name() + "/master"
+			db := newMockDBWithDSN(t, []string{master, t.Name() + "/slave-1"})
+			b := db.Batch(context.Background())
+			for _, query := range tt.queries {
+				b.Queue(query)
+			}
+			if b.Len() != len(tt.queries) {
+				t.Fatalf("%d statements queued, want %d", b.Len(), len(tt.queries))
+			}
+
+			results, err := b.Exec()
+			var batchErr *BatchError
+			switch {
+			case tt.failed < 0 && err != nil:
+				t.Fatal(err)
+			case tt.failed >= 0 && (!errors.As(err, &batchErr) || batchErr.Index != tt.failed):
+				t.Fatalf("error %v, want statement %d failed", err, tt.failed)
+			case tt.failed < 0 && len(results) != len(tt.queries):
+				t.Fatalf("%d results, want %d", len(results), len(tt.queries))
+			}
+			if got := execQueries(master); !slices.Equal(got, tt.want) {
+				t.Fatalf("executed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// benchDriver answer every query with a single row without doing any work,
+// so the benchmarks only measure the overhead of sqlt over sqlx
+type benchDriver struct{}
+
+type (
+	benchConn struct{}
+	benchRows struct{ done bool }
+)
+
+func init() {
+	sql.Register("sqltbench", benchDriver{})
+}
+
+func (benchDriver) Open(string) (driver.Conn, error) { return benchConn{}, nil }
+
+func (benchConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (benchConn) Close() error                        { return nil }
+func (benchConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (benchConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &benchRows{}, nil
+}
+
+func (benchConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (*benchRows) Columns() []string { return []string{"id"} }
+func (*benchRows) Close() error      { return nil }
+
+func (r *benchRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+func benchmarkGet(b *testing.B, get func(ctx context.Context, dest interface{}) error) {
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var id int64
+		if err := get(ctx, &id); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func openBench(b *testing.B, sources string) *DB {
+	db, err := Open("sqltbench", sources)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	return db
+}
+
+func BenchmarkGetSqlx(b *testing.B) {
+	db := sqlx.MustOpen("sqltbench", "master")
+	defer db.Close()
+	benchmarkGet(b, func(ctx context.Context, dest interface{}) error {
+		return db.GetContext(ctx, dest, "SELECT id FROM users WHERE id = ?", 1)
+	})
+}
+
+func BenchmarkGetSingleNode(b *testing.B) {
+	db := openBench(b, "master")
+	benchmarkGet(b, func(ctx context.Context, dest interface{}) error {
+		return db.GetContext(ctx, dest, "SELECT id FROM users WHERE id = ?", 1)
+	})
+}
+
+func BenchmarkGetReplicated(b *testing.B) {
+	db := openBench(b, "master;slave1;slave2")
+	benchmarkGet(b, func(ctx context.Context, dest interface{}) error {
+		return db.GetContext(ctx, dest, "SELECT id FROM users WHERE id = ?", 1)
+	})
+}
+
+func BenchmarkExecSqlx(b *testing.B) {
+	db := sqlx.MustOpen("sqltbench", "master")
+	defer db.Close()
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.ExecContext(ctx, "UPDATE users SET name = ? WHERE id = ?", "a", 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExecSingleNode(b *testing.B) {
+	db := openBench(b, "master")
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.ExecContext(ctx, "UPDATE users SET name = ? WHERE id = ?", "a", 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// NodeResult is the result of a statement executed on a single node
+type NodeResult struct {
+	Node   string
+	Result sql.Result
+	Err    error
+}
+
+// ExecAll execute the statement on master and every slave concurrently, e.g. for ANALYZE or
+// administrative statements. Every node result is returned, the error join every node error
+func (db *DB) ExecAll(ctx context.Context, query string, args ...interface{}) ([]NodeResult, error) {
+	if !db.enter() {
+		return nil, ErrShutdown
+	}
+	defer db.leave()
+
+	nodes := db.members()
+	results := make([]NodeResult, len(nodes))
+	wg := sync.WaitGroup{}
+	for i, n := range nodes {
+		wg.Add(1)
+		go func(i int, n *node) {
+			defer wg.Done()
+			var result sql.Result
+			err := n.injected(ctx)
+			if err == nil {
+				result, err = n.db().ExecContext(ctx, query, args...)
+			}
+			results[i] = NodeResult{Node: n.name, Result: result, Err: wrapError(n.track(err), n, "ExecAll", query)}
+		}(i, n)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return results, errors.Join(errs...)
+}
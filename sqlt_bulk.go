@@ -0,0 +1,164 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// ErrInvalidBulkRows is returned when BulkInsert rows is not a slice of struct
+var ErrInvalidBulkRows = errors.New("Bulk insert rows must be a slice of struct")
+
+type bulkOptions struct {
+	batchSize int
+	inTx      bool
+}
+
+// BulkOption configure BulkInsert
+type BulkOption func(*bulkOptions)
+
+// BulkBatchSize limit the number of rows of a single INSERT, the batch is always
+// small enough to stay under the placeholder limit of the driver
+func BulkBatchSize(rows int) BulkOption {
+	return func(o *bulkOptions) {
+		o.batchSize = rows
+	}
+}
+
+// BulkInTx insert all the batches in a single transaction
+func BulkInTx() BulkOption {
+	return func(o *bulkOptions) {
+		o.inTx = true
+	}
+}
+
+// maxPlaceholders return the number of placeholders a single statement of the dialect can hold
+func maxPlaceholders(d Dialect) int {
+	switch d {
+	case DialectSQLite:
+		return 999
+	case DialectSQLServer:
+		return 2000
+	}
+	return 65535
+}
+
+// BulkInsert insert rows, a slice of struct or pointer to struct, into table on master
+// using multi-row INSERTs. Columns are the db tagged fields of the struct.
+// Return the number of rows affected
+func (db *DB) BulkInsert(ctx context.Context, table string, rows interface{}, opts ...BulkOption) (int64, error) {
+	var o bulkOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(rows))
+	if v.Kind() != reflect.Slice {
+		return 0, ErrInvalidBulkRows
+	}
+	if v.Len() == 0 {
+		return 0, nil
+	}
+	t := reflectx.Deref(v.Type().Elem())
+	if t.Kind() != reflect.Struct {
+		return 0, ErrInvalidBulkRows
+	}
+
+	fields := bulkFields(db.Master().Mapper.TypeMap(t))
+	if len(fields) == 0 {
+		return 0, ErrInvalidBulkRows
+	}
+	columns := make([]string, len(fields))
+	for i, fi := range fields {
+		columns[i] = fi.Name
+	}
+
+	master := db.nodeAt(db.master())
+	bind := master.bind
+	batch := maxPlaceholders(master.dialect) / len(fields)
+	if o.batchSize > 0 && o.batchSize < batch {
+		batch = o.batchSize
+	}
+	if batch < 1 {
+		return 0, fmt.Errorf("table %s has more columns than placeholders allowed", table)
+	}
+
+	insert := func(exec func(query string, args []interface{}) (sql.Result, error)) (int64, error) {
+		var total int64
+		for start := 0; start < v.Len(); start += batch {
+			end := min(start+batch, v.Len())
+			query, args := bulkQuery(bind, table, columns, fields, v.Slice(start, end))
+			result, err := exec(query, args)
+			if err != nil {
+				return total, err
+			}
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return total, err
+			}
+			total += affected
+		}
+		return total, nil
+	}
+
+	if o.inTx {
+		var total int64
+		err := db.InTx(ctx, nil, func(tx *sqlx.Tx) error {
+			var err error
+			total, err = insert(func(query string, args []interface{}) (sql.Result, error) {
+				return tx.ExecContext(ctx, query, args...)
+			})
+			return err
+		})
+		return total, err
+	}
+	return insert(func(query string, args []interface{}) (sql.Result, error) {
+		return db.ExecContext(ctx, query, args...)
+	})
+}
+
+// bulkFields return the fields inserted as column, fields of embedded structs are flattened
+// and nested structs are only inserted when they are scanned as a single value
+func bulkFields(tm *reflectx.StructMap) []*reflectx.FieldInfo {
+	var fields []*reflectx.FieldInfo
+	for _, fi := range tm.Index {
+		if fi.Embedded || strings.Contains(fi.Path, ".") {
+			continue
+		}
+		if scannable(fi.Field.Type) || isLeaf(fi) {
+			fields = append(fields, fi)
+		}
+	}
+	return fields
+}
+
+func isLeaf(fi *reflectx.FieldInfo) bool {
+	for _, child := range fi.Children {
+		if child != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// bulkQuery build the multi-row INSERT of rows with the bindvar
+func bulkQuery(bind int, table string, columns []string, fields []*reflectx.FieldInfo, rows reflect.Value) (string, []interface{}) {
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(fields)), ", ") + ")"
+	values := make([]string, rows.Len())
+	args := make([]interface{}, 0, rows.Len()*len(fields))
+	for i := 0; i < rows.Len(); i++ {
+		values[i] = row
+		r := reflect.Indirect(rows.Index(i))
+		for _, fi := range fields {
+			args = append(args, reflectx.FieldByIndexesReadOnly(r, fi.Index).Interface())
+		}
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, strings.Join(columns, ", "), strings.Join(values, ", "))
+	return sqlx.Rebind(bind, query), args
+}
@@ -0,0 +1,82 @@
+package sqlt
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type bulkUser struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+// execQueries return the statements executed on the DSN, INSERT statements are shortened
+// to their number of arguments
+func execQueries(dsn string) []string {
+	var queries []string
+	for _, e := range mockExecs(dsn) {
+		if strings.HasPrefix(e.query, "INSERT") {
+			queries = append(queries, "INSERT "+strings.Repeat("?", e.args))
+			continue
+		}
+		queries = append(queries, e.query)
+	}
+	return queries
+}
+
+func TestBulkInsert(t *testing.T) {
+	users := func(n int) []bulkUser {
+		rows := make([]bulkUser, n)
+		for i := range rows {
+			rows[i] = bulkUser{ID: i + 1, Name: "user"}
+		}
+		return rows
+	}
+	tests := []struct {
+		name     string
+		rows     interface{}
+		opts     []BulkOption
+		want     []string
+		affected int64
+		wantErr  error
+	}{
+		{name: "single batch", rows: users(3), want: []string{"INSERT ??????"}, affected: 3},
+		{
+			name:     "batch size",
+			rows:     users(5),
+			opts:     []BulkOption{BulkBatchSize(2)},
+			want:     []string{"INSERT ????", "INSERT ????", "INSERT ??"},
+			affected: 5,
+		},
+		{
+			name:     "in transaction",
+			rows:     users(3),
+			opts:     []BulkOption{BulkBatchSize(2), BulkInTx()},
+			want:     []string{"BEGIN", "INSERT ????", "INSERT ??", "COMMIT"},
+			affected: 3,
+		},
+		{name: "pointers", rows: []*bulkUser{{ID: 1}, {ID: 2}}, want: []string{"INSERT ????"}, affected: 2},
+		{name: "empty", rows: []bulkUser{}},
+		{name: "not a slice", rows: bulkUser{}, wantErr: ErrInvalidBulkRows},
+		{name: "not a struct", rows: []int{1, 2}, wantErr: ErrInvalidBulkRows},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			master := t.Name() + "/master"
+			db := newMockDBWithDSN(t, []string{master, t.Name() + "/slave-1"})
+			affected, err := db.BulkInsert(context.Background(), "users", tt.rows, tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			if affected != tt.affected {
+				t.Fatalf("%d rows affected, want %d", affected, tt.affected)
+			}
+			if got := execQueries(master); !slices.Equal(got, tt.want) {
+				t.Fatalf("executed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package sqlt
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore store the cached query results, tags are the tables read by the query and the key
+// set by WithCacheKey, Invalidate drop every result having one of the tags
+type CacheStore interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error
+	Invalidate(ctx context.Context, tags ...string) error
+}
+
+// WithCache cache the result of slave Get and Select for ttl, results are invalidated by
+// Exec and NamedExec of the tables they read, or of the keys set by WithInvalidateKeys.
+// Writes inside transactions invalidate the cache on commit. Results are stored as JSON,
+// reads into a dest with unexported or `json:"-"` fields are not cached since the cached copy
+// would lose them. Tables are matched without schema and case, e.g. "public.Users" and "users"
+func WithCache(store CacheStore, ttl time.Duration) Option {
+	return func(o *options) {
+		o.cache = store
+		o.cacheTTL = ttl
+	}
+}
+
+// WithCacheLagWindow set how long after the invalidation of a table the slave reads of the table are not
+// cached, a slave replaying the write late would otherwise fill the cache with the old result for the
+// whole ttl. Reads served by master are always cached. Default to one second
+func WithCacheLagWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.cacheLagWindow = d
+	}
+}
+
+const defaultCacheLagWindow = time.Second
+
+type noCacheKey struct{}
+
+// WithoutCache return a context which reads bypass the result cache
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+var readTableRegexp = regexp.MustCompile("(?i)\\b(?:from|join)\\s+([\\w.\"`]+)")
+
+// readTables return the tables read by the query
+func readTables(query string) []string {
+	var tables []string
+	for _, match := range readTableRegexp.FindAllStringSubmatch(query, -1) {
+		tables = append(tables, identQuoteReplacer.Replace(match[1]))
+	}
+	return tables
+}
+
+// tableTags return the cache tags of the tables, without schema and lower cased so reads and
+// writes naming the table differently share the tag
+func tableTags(tables []string) []string {
+	tags := make([]string, 0, len(tables))
+	for _, table := range tables {
+		if i := strings.LastIndexByte(table, '.'); i >= 0 {
+			table = table[i+1:]
+		}
+		tags = append(tags, strings.ToLower(table))
+	}
+	return tags
+}
+
+// cacheableTypes hold whether a dest type survive the JSON round trip of the cache
+var cacheableTypes sync.Map
+
+// cacheable report whether the dest keep every field through JSON, unexported fields
+// and fields tagged `json:"-"` are lost
+func cacheable(dest interface{}) bool {
+	t := reflect.TypeOf(dest)
+	if ok, found := cacheableTypes.Load(t); found {
+		return ok.(bool)
+	}
+	ok := jsonSafe(t, make(map[reflect.Type]bool))
+	cacheableTypes.Store(t, ok)
+	return ok
+}
+
+var jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+
+func jsonSafe(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] || t.Implements(jsonMarshaler) || reflect.PointerTo(t).Implements(jsonMarshaler) {
+		return true
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return jsonSafe(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Tag.Get("json") == "-" || (!f.IsExported() && !f.Anonymous) {
+				return false
+			}
+			if !jsonSafe(f.Type, seen) {
+				return false
+			}
+		}
+	case reflect.Interface, reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return false
+	}
+	return true
+}
+
+// cacheKey return the key of the call result, false when the call is not cached
+func (db *DB) cacheKey(ctx context.Context, c call) (string, bool) {
+	if db.opts.cache == nil || c.write || c.master || c.dest == nil || ctx.Value(noCacheKey{}) != nil {
+		return "", false
+	}
+	if consistencySession(ctx) != nil || pinned(ctx) || c.steered() || !cacheable(c.dest) {
+		return "", false
+	}
+	args, err := json.Marshal(c.args)
+	if err != nil {
+		args = []byte(fmt.Sprintf("%#v", c.args))
+	}
+	h := sha256.New()
+	h.Write([]byte(strings.Join(strings.Fields(c.query), " ")))
+	h.Write([]byte{0})
+	h.Write(args)
+	return "sqlt:" + db.groupName + ":" + c.op + ":" + hex.EncodeToString(h.Sum(nil)), true
+}
+
+// cached serve the read from the cache, or run fn and cache its result
+func (db *DB) cached(ctx context.Context, c call, fn func(ctx context.Context) error) error {
+	key, ok := db.cacheKey(ctx, c)
+	if !ok {
+		return fn(ctx)
+	}
+	if value, hit, err := db.opts.cache.Get(ctx, key); err == nil && hit {
+		if json.Unmarshal(value, c.dest) == nil {
+			return nil
+		}
+	}
+	// the metadata tell which node served the read
+	meta := resultMetadata(ctx)
+	if meta == nil {
+		ctx, meta = WithResultMetadata(ctx)
+	}
+	start := time.Now()
+	gen := db.cacheGen.Load()
+	if err := fn(ctx); err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(c.dest)
+	if err != nil {
+		return nil
+	}
+	tags := tableTags(readTables(c.query))
+	if key, ok := ctx.Value(readKey).(string); ok {
+		tags = append(tags, key)
+	}
+
+	// a write invalidating the cache while the read was running may be missing from its result,
+	// the invalidation wait for the fill so the result is either dropped or invalidated
+	db.cacheMu.RLock()
+	defer db.cacheMu.RUnlock()
+	if db.cacheGen.Load() != gen || (meta.Node != db.nodeAt(db.master()).name && db.lagging(tags, start)) {
+		return nil
+	}
+	if err := db.opts.cache.Set(ctx, key, value, db.opts.cacheTTL, tags); err != nil {
+		db.opts.logger.Printf("sqlt: cache set failed: %v", err)
+	}
+	return nil
+}
+
+// invalidateCache drop the results invalidated by the write
+func (db *DB) invalidateCache(ctx context.Context, c call) {
+	if db.opts.cache == nil {
+		return
+	}
+	keys, ok := ctx.Value(invalidateKey).([]string)
+	if !ok {
+		keys = tableTags(writtenTables(c.query))
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	db.cacheMu.Lock()
+	db.cacheGen.Add(1)
+	db.invalidated(keys, time.Now())
+	db.cacheMu.Unlock()
+	if err := db.opts.cache.Invalidate(ctx, keys...); err != nil {
+		db.opts.logger.Printf("sqlt: cache invalidation failed: %v", err)
+	}
+}
+
+// invalidated record the invalidation time of the tags, must be called with cacheMu held.
+// Tags invalidated before the lag window are dropped once the map doubled since the last drop
+func (db *DB) invalidated(tags []string, at time.Time) {
+	if db.cacheInvalidated == nil {
+		db.cacheInvalidated = make(map[string]time.Time)
+	}
+	for _, tag := range tags {
+		db.cacheInvalidated[tag] = at
+	}
+	if len(db.cacheInvalidated) < db.cachePruneAt {
+		return
+	}
+	for tag, t := range db.cacheInvalidated {
+		if at.Sub(t) > db.opts.cacheLagWindow {
+			delete(db.cacheInvalidated, tag)
+		}
+	}
+	db.cachePruneAt = 2*len(db.cacheInvalidated) + 64
+}
+
+// lagging report whether one of the tags was invalidated within the lag window before the read
+// started, a slave may not have replayed the write yet. Must be called with cacheMu held
+func (db *DB) lagging(tags []string, start time.Time) bool {
+	for _, tag := range tags {
+		if t, ok := db.cacheInvalidated[tag]; ok && start.Sub(t) < db.opts.cacheLagWindow {
+			return true
+		}
+	}
+	return false
+}
+
+// MemoryCache is an in-memory CacheStore keeping the most recently used entries
+type MemoryCache struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru hold the entries, most recently used first
+	lru  *list.List
+	tags map[string]map[string]struct{}
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+	tags    []string
+}
+
+// NewMemoryCache return an empty in-memory cache holding up to size entries, the least recently
+// used entry is evicted when the cache is full. Zero size default to 10000 entries
+func NewMemoryCache(size int) *MemoryCache {
+	if size <= 0 {
+		size = defaultMemoryCacheSize
+	}
+	return &MemoryCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		tags:    make(map[string]map[string]struct{}),
+	}
+}
+
+const defaultMemoryCacheSize = 10000
+
+// Get the value of key
+func (m *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := e.Value.(*memoryEntry)
+	if time.Now().After(entry.expires) {
+		m.remove(key)
+		return nil, false, nil
+	}
+	m.lru.MoveToFront(e)
+	return entry.value, true, nil
+}
+
+// Set the value of key for ttl, expired entries are dropped from the least recently used
+// end and the least recently used entry is evicted when the cache is full
+func (m *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(key)
+
+	now := time.Now()
+	for e := m.lru.Back(); e != nil; e = m.lru.Back() {
+		entry := e.Value.(*memoryEntry)
+		if m.lru.Len() < m.size && !now.After(entry.expires) {
+			break
+		}
+		m.remove(entry.key)
+	}
+
+	m.entries[key] = m.lru.PushFront(&memoryEntry{key: key, value: value, expires: now.Add(ttl), tags: tags})
+	for _, tag := range tags {
+		if m.tags[tag] == nil {
+			m.tags[tag] = make(map[string]struct{})
+		}
+		m.tags[tag][key] = struct{}{}
+	}
+	return nil
+}
+
+// Invalidate drop every value having one of the tags
+func (m *MemoryCache) Invalidate(ctx context.Context, tags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, tag := range tags {
+		for key := range m.tags[tag] {
+			m.remove(key)
+		}
+	}
+	return nil
+}
+
+// Len return the number of entries, including the expired entries not dropped yet
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lru.Len()
+}
+
+// remove the key and its tags, must be called with mu held
+func (m *MemoryCache) remove(key string) {
+	e, ok := m.entries[key]
+	if !ok {
+		return
+	}
+	entry := m.lru.Remove(e).(*memoryEntry)
+	delete(m.entries, key)
+	for _, tag := range entry.tags {
+		delete(m.tags[tag], key)
+		if len(m.tags[tag]) == 0 {
+			delete(m.tags, tag)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package sqlt
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheFill(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		// write run before the reads, empty for none
+		write string
+		// wait between the write and the reads
+		wait time.Duration
+		// cached is whether the second read is served by the cache
+		cached bool
+	}{
+		{name: "slave read cached", cached: true},
+		{name: "write of another table", write: "UPDATE orders SET total = 0", cached: true},
+		{name: "slave read within the lag window", write: "UPDATE users SET name = ''", cached: false},
+		{
+			name:   "slave read after the lag window",
+			opts:   []Option{WithCacheLagWindow(10 * time.Millisecond)},
+			write:  "UPDATE users SET name = ''",
+			wait:   20 * time.Millisecond,
+			cached: true,
+		},
+		{name: "lag window disabled", opts: []Option{WithCacheLagWindow(0)}, write: "UPDATE users SET name = ''", cached: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDB(t, 1, append(tt.opts, WithCache(NewMemoryCache(0), time.Minute))...)
+			if tt.write != "" {
+				if _, err := db.Exec(tt.write); err != nil {
+					t.Fatal(err)
+				}
+				time.Sleep(tt.wait)
+			}
+
+			var dsn string
+			if err := db.Get(&dsn, "SELECT name FROM users"); err != nil {
+				t.Fatal(err)
+			}
+			// a cache hit doesn't reach a node
+			ctx, meta := WithResultMetadata(context.Background())
+			if err := db.GetContext(ctx, &dsn, "SELECT name FROM users"); err != nil {
+				t.Fatal(err)
+			}
+			if cached := meta.Node == ""; cached != tt.cached {
+				t.Fatalf("cached %v, want %v: served by %q", cached, tt.cached, meta.Node)
+			}
+		})
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	db := newMockDB(t, 1, WithCache(NewMemoryCache(0), time.Minute), WithCacheLagWindow(0))
+	var dsn string
+	if err := db.Get(&dsn, "SELECT name FROM users"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("UPDATE public.Users SET name = ''"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, meta := WithResultMetadata(context.Background())
+	if err := db.GetContext(ctx, &dsn, "SELECT name FROM users"); err != nil {
+		t.Fatal(err)
+	}
+	if meta.Node == "" {
+		t.Fatal("read served by the cache after the write")
+	}
+}
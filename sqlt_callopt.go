@@ -0,0 +1,158 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CallOption override the routing of a single read, see GetOpt
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	node           string
+	maxStaleness   time.Duration
+	masterFallback bool
+}
+
+// PreferNode serve the read from the named node while it is in rotation,
+// otherwise the read is routed as usual
+func PreferNode(name string) CallOption {
+	return func(o *callOptions) {
+		o.node = name
+	}
+}
+
+// MaxStaleness serve the read from a slave which lag is at most d, or master when no slave
+// is fresh enough. The lag is measured by the LagProbe, slaves with unknown lag are skipped
+func MaxStaleness(d time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.maxStaleness = d
+	}
+}
+
+// MasterFallback serve the read from master instead of another slave when the preferred
+// node is out of rotation
+func MasterFallback() CallOption {
+	return func(o *callOptions) {
+		o.masterFallback = true
+	}
+}
+
+func newCallOptions(opts []CallOption) *callOptions {
+	if len(opts) == 0 {
+		return nil
+	}
+	o := &callOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// steered report whether the read routing is overridden by call options
+func (c call) steered() bool {
+	return c.opts != nil
+}
+
+// pickOpt return the node satisfying the call options, nil when the read is routed as usual
+func (db *DB) pickOpt(o *callOptions) *node {
+	r := db.route.Load()
+	fresh := func(n *node) bool {
+		return o.maxStaleness <= 0 || db.within(n, o.maxStaleness)
+	}
+
+	if o.node != "" {
+		if n, err := db.node(o.node); err == nil && r.serving(n) && fresh(n) {
+			return n
+		}
+		if o.masterFallback {
+			return db.nodeAt(r.master)
+		}
+	}
+	if o.maxStaleness <= 0 {
+		return nil
+	}
+	if len(r.slaves) == 0 {
+		return db.nodeAt(r.master)
+	}
+
+	// start from the balancer pick so the fresh slaves share the load
+	first := db.balancer.pick(r)
+	start := 0
+	for i, n := range r.slaves {
+		if n == first {
+			start = i
+		}
+	}
+	for i := range r.slaves {
+		if n := r.slaves[(start+i)%len(r.slaves)]; fresh(n) {
+			return n
+		}
+	}
+	return db.nodeAt(r.master)
+}
+
+// GetOpt using slave, routed by the call options
+func (db *DB) GetOpt(ctx context.Context, dest interface{}, query string, args []interface{}, opts ...CallOption) error {
+	return db.runRead(ctx, call{op: "Get", query: query, args: args, dest: dest, opts: newCallOptions(opts)}, func(ctx context.Context, n *node, query string, dest interface{}) error {
+		q, done := db.queryer(ctx, n, query)
+		defer done()
+		return q.GetContext(ctx, dest, query, args...)
+	})
+}
+
+// SelectOpt using slave, routed by the call options
+func (db *DB) SelectOpt(ctx context.Context, dest interface{}, query string, args []interface{}, opts ...CallOption) error {
+	return db.runRead(ctx, call{op: "Select", query: query, args: args, dest: dest, opts: newCallOptions(opts)}, func(ctx context.Context, n *node, query string, dest interface{}) error {
+		q, done := db.queryer(ctx, n, query)
+		defer done()
+		return q.SelectContext(ctx, dest, query, args...)
+	})
+}
+
+// QueryOpt queries the slave routed by the call options and returns an *sql.Rows.
+func (db *DB) QueryOpt(ctx context.Context, query string, args []interface{}, opts ...CallOption) (*sql.Rows, error) {
+	var rows *sql.Rows
+	c := call{op: "Query", query: query, args: args, stream: true, opts: newCallOptions(opts)}
+	err := db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+		q, done := db.queryer(ctx, n, query)
+		defer done()
+		var err error
+		rows, err = q.QueryContext(ctx, query, args...)
+		db.leaks.track(c, n, rows)
+		return err
+	})
+	return rows, err
+}
+
+// QueryxOpt queries the slave routed by the call options and returns an *sqlx.Rows.
+func (db *DB) QueryxOpt(ctx context.Context, query string, args []interface{}, opts ...CallOption) (*sqlx.Rows, error) {
+	var rows *sqlx.Rows
+	c := call{op: "Queryx", query: query, args: args, stream: true, opts: newCallOptions(opts)}
+	err := db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+		q, done := db.queryer(ctx, n, query)
+		defer done()
+		var err error
+		rows, err = q.QueryxContext(ctx, query, args...)
+		if err == nil {
+			db.leaks.track(c, n, rows.Rows)
+		}
+		return err
+	})
+	return rows, err
+}
+
+// QueryRowxOpt queries the slave routed by the call options and returns an *sqlx.Row.
+func (db *DB) QueryRowxOpt(ctx context.Context, query string, args []interface{}, opts ...CallOption) *sqlx.Row {
+	var row *sqlx.Row
+	db.run(ctx, call{op: "QueryRowx", query: query, args: args, stream: true, opts: newCallOptions(opts)}, func(ctx context.Context, n *node, query string) error {
+		q, done := db.queryer(ctx, n, query)
+		defer done()
+		row = q.QueryRowxContext(ctx, query, args...)
+		return row.Err()
+	})
+	return row
+}
@@ -0,0 +1,70 @@
+package sqlt
+
+import (
+	"context"
+	"time"
+)
+
+// fault is injected into the node by FailNode and DelayNode
+type fault struct {
+	err   error
+	delay time.Duration
+}
+
+// FailNode make every query and ping to the node fail with err until RecoverNode is called,
+// use this in tests to simulate a node going down. Ping must run for the node to be evicted
+func (db *DB) FailNode(name string, err error) error {
+	return db.injectFault(name, func(f *fault) {
+		f.err = err
+	})
+}
+
+// DelayNode add d to every query and ping to the node until RecoverNode is called,
+// use this in tests to simulate a slow or lagging node
+func (db *DB) DelayNode(name string, d time.Duration) error {
+	return db.injectFault(name, func(f *fault) {
+		f.delay = d
+	})
+}
+
+// RecoverNode remove every injected failure and delay from the node
+func (db *DB) RecoverNode(name string) error {
+	n, err := db.node(name)
+	if err != nil {
+		return err
+	}
+	n.fault.Store(nil)
+	return nil
+}
+
+func (db *DB) injectFault(name string, fn func(*fault)) error {
+	n, err := db.node(name)
+	if err != nil {
+		return err
+	}
+	f := new(fault)
+	if current := n.fault.Load(); current != nil {
+		*f = *current
+	}
+	fn(f)
+	n.fault.Store(f)
+	return nil
+}
+
+// injected apply the injected fault of the node, if any
+func (n *node) injected(ctx context.Context) error {
+	f := n.fault.Load()
+	if f == nil {
+		return nil
+	}
+	if f.delay > 0 {
+		timer := time.NewTimer(f.delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return f.err
+}
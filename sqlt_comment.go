@@ -0,0 +1,57 @@
+package sqlt
+
+import (
+	"context"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+type commentKey struct{}
+
+// WithQueryComments append a sqlcommenter style comment with the group name, the node name and
+// the tags set by WithCommentTags to every query, so the load seen in pg_stat_activity or the
+// slow log can be attributed. WithStmtCache is disabled, the comment is part of the statement
+func WithQueryComments() Option {
+	return func(o *options) {
+		o.queryComments = true
+		o.pipeline = true
+	}
+}
+
+// WithCommentTags return a context adding the tags, e.g. the trace id or the route,
+// to the comment of its queries
+func WithCommentTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string)
+	if parent, ok := ctx.Value(commentKey{}).(map[string]string); ok {
+		maps.Copy(merged, parent)
+	}
+	maps.Copy(merged, tags)
+	return context.WithValue(ctx, commentKey{}, merged)
+}
+
+// comment return the query with the comment of the node appended
+func (db *DB) comment(ctx context.Context, n *node, query string) string {
+	if !db.opts.queryComments {
+		return query
+	}
+
+	tags := map[string]string{"db_group": db.groupName, "db_node": n.name}
+	if extra, ok := ctx.Value(commentKey{}).(map[string]string); ok {
+		maps.Copy(tags, extra)
+	}
+	pairs := make([]string, 0, len(tags))
+	for _, k := range slices.Sorted(maps.Keys(tags)) {
+		pairs = append(pairs, commentEscape(k)+"='"+commentEscape(tags[k])+"'")
+	}
+	comment := "/*" + strings.Join(pairs, ",") + "*/"
+
+	trimmed := strings.TrimRight(query, " \t\n;")
+	return trimmed + " " + comment + query[len(trimmed):]
+}
+
+// commentEscape URL encode s like sqlcommenter, ':' is encoded so named queries don't bind it
+func commentEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,215 @@
+package sqlt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describe a connection group, it can be loaded from a YAML or JSON document
+type Config struct {
+	Name     string       `json:"name" yaml:"name"`
+	Driver   string       `json:"driver" yaml:"driver"`
+	Master   NodeConfig   `json:"master" yaml:"master"`
+	Replicas []NodeConfig `json:"replicas" yaml:"replicas"`
+	// Pool is applied to every node without its own pool
+	Pool      PoolSettings    `json:"pool" yaml:"pool"`
+	Heartbeat HeartbeatConfig `json:"heartbeat" yaml:"heartbeat"`
+	// ReadPreference is parsed by ParseReadPreference, e.g. "tag=az:us-east-1a, fallback=any"
+	ReadPreference string `json:"read_preference" yaml:"read_preference"`
+}
+
+// NodeConfig describe a single node, ${ENV_VAR} in the DSN is expanded from the environment
+type NodeConfig struct {
+	Name         string            `json:"name" yaml:"name"`
+	DSN          string            `json:"dsn" yaml:"dsn"`
+	Weight       int               `json:"weight" yaml:"weight"`
+	Label        string            `json:"label" yaml:"label"`
+	Tags         map[string]string `json:"tags" yaml:"tags"`
+	Pool         *PoolSettings     `json:"pool" yaml:"pool"`
+	PingInterval Duration          `json:"ping_interval" yaml:"ping_interval"`
+}
+
+// PoolSettings is the file representation of PoolConfig
+type PoolSettings struct {
+	MaxOpenConns    int      `json:"max_open_conns" yaml:"max_open_conns"`
+	MaxIdleConns    int      `json:"max_idle_conns" yaml:"max_idle_conns"`
+	ConnMaxLifetime Duration `json:"conn_max_lifetime" yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime Duration `json:"conn_max_idle_time" yaml:"conn_max_idle_time"`
+}
+
+// HeartbeatConfig configure the heartbeat of the group
+type HeartbeatConfig struct {
+	Enabled          bool     `json:"enabled" yaml:"enabled"`
+	PingTimeout      Duration `json:"ping_timeout" yaml:"ping_timeout"`
+	ReconnectTimeout Duration `json:"reconnect_timeout" yaml:"reconnect_timeout"`
+}
+
+// Duration is a time.Duration written as string like "30s", or as number of nanoseconds
+type Duration time.Duration
+
+// UnmarshalJSON parse the duration
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		s = string(b)
+	}
+	return d.parse(s)
+}
+
+// UnmarshalYAML parse the duration
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	return d.parse(value.Value)
+}
+
+func (d *Duration) parse(s string) error {
+	if ns, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*d = Duration(ns)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (p PoolSettings) config() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    p.MaxOpenConns,
+		MaxIdleConns:    p.MaxIdleConns,
+		ConnMaxLifetime: time.Duration(p.ConnMaxLifetime),
+		ConnMaxIdleTime: time.Duration(p.ConnMaxIdleTime),
+	}
+}
+
+// LoadConfig read the config file, the format is chosen by the extension: .json, .yaml or .yml
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	switch filepath.Ext(path) {
+	case ".json":
+		err = json.Unmarshal(b, &cfg)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &cfg)
+	default:
+		return cfg, fmt.Errorf("unknown config format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// OpenFromFile open the connection group described by the config file, see LoadConfig
+func OpenFromFile(path string, opts ...Option) (*DB, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return OpenFromConfig(cfg, opts...)
+}
+
+// OpenFromConfig open the connection group described by cfg, opts are applied after the config
+func OpenFromConfig(cfg Config, opts ...Option) (*DB, error) {
+	srcs, cfgOpts, err := cfg.sources()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := openSources(context.Background(), cfg.Driver, srcs, cfg.Name, append(cfgOpts, opts...))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Heartbeat.Enabled {
+		db.DoHeartBeat()
+	}
+	return db, db.Ping()
+}
+
+var envRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replace ${ENV_VAR} with the environment variable, other $ are kept as is
+func expandEnv(s string) (string, error) {
+	var missing []string
+	expanded := envRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		name := envRegexp.FindStringSubmatch(match)[1]
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return val
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %v is not set", missing)
+	}
+	return expanded, nil
+}
+
+// sources convert the config into sources and options
+func (cfg Config) sources() ([]source, []Option, error) {
+	if cfg.Driver == "" {
+		return nil, nil, errors.New("No driver configured")
+	}
+	if cfg.Master.DSN == "" {
+		return nil, nil, errors.New("No master configured")
+	}
+
+	var opts []Option
+	if cfg.ReadPreference != "" {
+		pref, err := ParseReadPreference(cfg.ReadPreference)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, WithReadPreference(pref))
+	}
+	if d := time.Duration(cfg.Heartbeat.PingTimeout); d > 0 {
+		opts = append(opts, WithPingTimeout(d))
+	}
+	if d := time.Duration(cfg.Heartbeat.ReconnectTimeout); d > 0 {
+		opts = append(opts, WithReconnectTimeout(d))
+	}
+
+	nodes := append([]NodeConfig{cfg.Master}, cfg.Replicas...)
+	srcs := make([]source, len(nodes))
+	for i, nc := range nodes {
+		dsn, err := expandEnv(nc.DSN)
+		if err != nil {
+			return nil, nil, err
+		}
+		src := source{name: nc.Name, dsn: dsn, weight: nc.Weight, label: nc.Label, tags: copyLabels(nc.Tags)}
+		if src.name == "" {
+			src.name = "master"
+			if i > 0 {
+				src.name = "slave-" + strconv.Itoa(i)
+			}
+		}
+		if src.weight < 1 {
+			src.weight = 1
+		}
+		srcs[i] = src
+
+		pool := cfg.Pool
+		if nc.Pool != nil {
+			pool = *nc.Pool
+		}
+		opts = append(opts, WithPoolConfig(src.name, pool.config()))
+		if d := time.Duration(nc.PingInterval); d > 0 {
+			opts = append(opts, WithPingInterval(src.name, d))
+		}
+	}
+	return srcs, opts, nil
+}
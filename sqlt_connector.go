@@ -0,0 +1,220 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// OnConnectFunc is called with every new connection of the node before it is used
+type OnConnectFunc func(ctx context.Context, conn *sql.Conn, nodeName string) error
+
+// WithOnConnect run fn on every new connection of every node, e.g. to set the search_path or time zone,
+// the connection is discarded when fn return error
+func WithOnConnect(fn OnConnectFunc) Option {
+	return func(o *options) {
+		o.onConnect = fn
+	}
+}
+
+// WithDriverWrapper wrap the driver of every node, e.g. with sqlhooks or otelsql instrumentation.
+// Drivers registered under another name can be set per node with the driver attribute instead
+func WithDriverWrapper(wrap func(driver.Driver) driver.Driver) Option {
+	return func(o *options) {
+		o.driverWrapper = wrap
+	}
+}
+
+// openNode open the connection pool of the node with the mapper and unsafe mode of the DB
+func (db *DB) openNode(name, driverName, dsn string) (*sqlx.DB, error) {
+	conn, err := db.openConn(name, driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return db.customize(conn), nil
+}
+
+// openConn open the connection pool, wrapping the driver connector when a connect hook,
+// a credential provider or a driver wrapper is set
+func (db *DB) openConn(name, driverName, dsn string) (*sqlx.DB, error) {
+	if db.opts.onConnect == nil && db.opts.credentials == nil && db.opts.driverWrapper == nil {
+		return sqlx.Open(driverName, dsn)
+	}
+
+	// sql.Open is only used to retrieve the registered driver, it doesn't connect
+	probe, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := probe.Driver()
+	probe.Close()
+	if db.opts.driverWrapper != nil {
+		drv = db.opts.driverWrapper(drv)
+	}
+
+	var (
+		connector  driver.Connector
+		credential *credentialConnector
+	)
+	if db.opts.credentials != nil {
+		credential = &credentialConnector{name: name, dsn: dsn, provider: db.opts.credentials, driver: drv}
+		connector = credential
+	} else if connector, err = newConnector(drv, dsn); err != nil {
+		return nil, err
+	}
+	if db.opts.onConnect != nil {
+		connector = hookConnector{Connector: connector, name: name, hook: db.opts.onConnect}
+	}
+
+	pool := sql.OpenDB(connector)
+	if credential != nil {
+		credential.mu.Lock()
+		credential.pool = pool
+		credential.mu.Unlock()
+		credentialPools.Store(pool, credential)
+	}
+	return sqlx.NewDb(pool, driverName), nil
+}
+
+// newConnector return the connector of the driver for dsn
+func newConnector(drv driver.Driver, dsn string) (driver.Connector, error) {
+	if dc, ok := drv.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+	return dsnConnector{dsn: dsn, driver: drv}, nil
+}
+
+// dsnConnector is the connector of drivers not implementing driver.DriverContext
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// hookConnector run the connect hook on every new connection
+type hookConnector struct {
+	driver.Connector
+	name string
+	hook OnConnectFunc
+}
+
+func (c hookConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.setup(ctx, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Close close the wrapped connector, called when the pool is closed
+func (c hookConnector) Close() error {
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// setup expose the driver connection as *sql.Conn through a single connection pool,
+// the pool never close the driver connection so it can be returned to the node pool
+func (c hookConnector) setup(ctx context.Context, conn driver.Conn) error {
+	pool := sql.OpenDB(singleConnector{conn: conn, driver: c.Driver()})
+	defer pool.Close()
+
+	sqlConn, err := pool.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer sqlConn.Close()
+	return c.hook(ctx, sqlConn, c.name)
+}
+
+// singleConnector always return the same connection
+type singleConnector struct {
+	conn   driver.Conn
+	driver driver.Driver
+}
+
+func (c singleConnector) Connect(context.Context) (driver.Conn, error) {
+	return keepConn{forwardConn{c.conn}}, nil
+}
+
+func (c singleConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// keepConn ignore Close and forward the optional context interfaces of the connection
+type keepConn struct {
+	forwardConn
+}
+
+func (c keepConn) Close() error {
+	return nil
+}
+
+// forwardConn forward the optional context interfaces of the wrapped connection
+type forwardConn struct {
+	driver.Conn
+}
+
+func (c forwardConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := c.Conn.(driver.ExecerContext); ok {
+		return execer.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c forwardConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := c.Conn.(driver.QueryerContext); ok {
+		return queryer.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c forwardConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c forwardConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c forwardConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c forwardConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c forwardConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
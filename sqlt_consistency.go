@@ -0,0 +1,219 @@
+package sqlt
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ReadYourWrites configure how the write position is captured on master and compared on slaves,
+// the defaults use the WAL LSN for postgres and GTID for mysql
+type ReadYourWrites struct {
+	// PositionQuery return the current write position of master
+	PositionQuery string
+	// ReplayedQuery return true when the slave replayed past the position given as the only argument
+	ReplayedQuery string
+}
+
+// WithReadYourWrites override the queries used by consistency sessions, see WithConsistency
+func WithReadYourWrites(config ReadYourWrites) Option {
+	return func(o *options) {
+		o.readYourWrites = config
+	}
+}
+
+func (db *DB) positionQuery(n *node) string {
+	if q := db.opts.readYourWrites.PositionQuery; q != "" {
+		return q
+	}
+	if n.dialect == DialectMySQL {
+		return "SELECT @@GLOBAL.gtid_executed"
+	}
+	return "SELECT pg_current_wal_lsn()"
+}
+
+func (db *DB) replayedQuery(n *node) string {
+	if q := db.opts.readYourWrites.ReplayedQuery; q != "" {
+		return q
+	}
+	if n.dialect == DialectMySQL {
+		return "SELECT GTID_SUBSET(?, @@GLOBAL.gtid_executed)"
+	}
+	return "SELECT pg_last_wal_replay_lsn() >= ?::pg_lsn"
+}
+
+// session is the write position of a consistency session
+type session struct {
+	mu       sync.Mutex
+	position string
+	// unknown is set when the position of the last write can't be captured, reads go to master
+	unknown bool
+}
+
+type sessionKey struct{}
+
+// WithConsistency return a context which read its own writes. Writes using the context capture
+// the master position, reads using it are served by a slave which replayed past the position or
+// master when no slave caught up. token continue the session of another request, see ConsistencyToken
+func WithConsistency(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{position: token})
+}
+
+// ConsistencyToken return the write position of the consistency session, pass it to WithConsistency
+// to read the writes in another request. Empty when the context has no session or no write
+func ConsistencyToken(ctx context.Context) string {
+	s := consistencySession(ctx)
+	if s == nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.position
+}
+
+func consistencySession(ctx context.Context) *session {
+	s, _ := ctx.Value(sessionKey{}).(*session)
+	return s
+}
+
+// capturePosition store the master position into the consistency session of the context, if any
+func (db *DB) capturePosition(ctx context.Context, n *node) {
+	s := consistencySession(ctx)
+	if s == nil {
+		return
+	}
+	var position string
+	if err := n.db().QueryRowContext(ctx, db.positionQuery(n)).Scan(&position); err != nil {
+		// without position the session can't trust any slave
+		s.mu.Lock()
+		s.unknown = true
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Lock()
+	s.position, s.unknown = position, false
+	s.mu.Unlock()
+}
+
+// consistentNode return a slave which replayed past the session position, preferring the balancer pick,
+// or master when no slave caught up. The slaves are checked concurrently and the results are cached
+// briefly, so a busy session doesn't query every slave on every read
+func (db *DB) consistentNode(ctx context.Context, s *session) *node {
+	s.mu.Lock()
+	position, unknown := s.position, s.unknown
+	s.mu.Unlock()
+
+	if unknown {
+		return db.nodeAt(db.master())
+	}
+	if position == "" {
+		return db.slave()
+	}
+
+	r := db.route.Load()
+	if r.total == 0 {
+		return db.nodeAt(r.master)
+	}
+	first := db.balancer.pick(r)
+	start := 0
+	for i, n := range r.slaves {
+		if n == first {
+			start = i
+		}
+	}
+	var check []*node
+	for i := range r.slaves {
+		n := r.slaves[(start+i)%len(r.slaves)]
+		caught, ok := n.replayed.get(position)
+		if !ok {
+			check = append(check, n)
+		} else if caught {
+			return n
+		}
+	}
+	if len(check) == 0 {
+		return db.nodeAt(r.master)
+	}
+
+	// the first slave which caught up serve the read, the other checks are canceled
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan *node, len(check))
+	for _, n := range check {
+		go func(n *node) {
+			var replayed string
+			if err := n.db().QueryRowContext(ctx, n.rebind(db.replayedQuery(n)), position).Scan(&replayed); err != nil {
+				results <- nil
+				return
+			}
+			caught := truthy(replayed)
+			n.replayed.set(position, caught)
+			if !caught {
+				n = nil
+			}
+			results <- n
+		}(n)
+	}
+	for range check {
+		if n := <-results; n != nil {
+			return n
+		}
+	}
+	return db.nodeAt(r.master)
+}
+
+const (
+	// a slave keep the positions it replayed, the result only expire in case the slave is rebuilt
+	replayedTTL = time.Second
+	// a slave behind the position may catch up any moment
+	behindTTL = 50 * time.Millisecond
+	// maxReplayed bound the positions cached per slave
+	maxReplayed = 1024
+)
+
+// replayCache remember whether the slave replayed past the recent session positions
+type replayCache struct {
+	mu      sync.Mutex
+	entries map[string]replayEntry
+}
+
+type replayEntry struct {
+	caught  bool
+	expires time.Time
+}
+
+// get return whether the slave replayed past the position, ok is false when it isn't cached
+func (c *replayCache) get(position string) (caught, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[position]
+	if !ok || time.Now().After(e.expires) {
+		return false, false
+	}
+	return e.caught, true
+}
+
+func (c *replayCache) set(position string, caught bool) {
+	ttl := behindTTL
+	if caught {
+		ttl = replayedTTL
+	}
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]replayEntry)
+	}
+	if len(c.entries) >= maxReplayed {
+		for position, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, position)
+			}
+		}
+		if len(c.entries) >= maxReplayed {
+			clear(c.entries)
+		}
+	}
+	c.entries[position] = replayEntry{caught: caught, expires: now.Add(ttl)}
+}
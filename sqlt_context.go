@@ -4,16 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"strconv"
+	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
-func open(ctx context.Context, driverName, sources string, groupName string) (*DB, error) {
-	var err error
-
+func open(ctx context.Context, driverName, sources string, groupName string, opts []Option) (*DB, error) {
 	conns := strings.Split(sources, ";")
 	connsLength := len(conns)
 
@@ -22,37 +21,71 @@ func open(ctx context.Context, driverName, sources string, groupName string) (*D
 		return nil, errors.New("No sources found")
 	}
 
-	db := &DB{
-		sqlxdb: make([]*sqlx.DB, connsLength),
-		stats:  make([]DbStatus, connsLength),
-	}
-	db.length = connsLength
-	db.driverName = driverName
-
+	srcs := make([]source, connsLength)
 	for i := range conns {
-		db.sqlxdb[i], err = sqlx.Open(driverName, conns[i])
+		src, err := parseSource(conns[i])
 		if err != nil {
-			db.inactivedb = append(db.inactivedb, i)
 			return nil, err
 		}
-		constatus := true
-
-		// set the name
-		name := ""
-		if i == 0 {
-			name = "master"
-		} else {
-			name = "slave-" + strconv.Itoa(i)
+		srcs[i] = src
+	}
+	return openSources(ctx, driverName, srcs, groupName, opts)
+}
+
+// openSources open a node for every parsed source, the first source is the master.
+// Sources without name are named following the node name format
+func openSources(ctx context.Context, driverName string, srcs []source, groupName string, opts []Option) (*DB, error) {
+	db := newDB(driverName, newOptions(opts))
+
+	for i, src := range srcs {
+		if src.name == "" {
+			src.name = db.nodeName(i, src.dsn)
+		}
+		if _, err := db.node(src.name); err == nil {
+			return nil, fmt.Errorf("duplicate node name %q", src.name)
 		}
 
-		status := DbStatus{
-			Name:       name,
-			Connected:  constatus,
-			LastActive: time.Now().String(),
+		addr, err := db.resolveAddress(ctx, src.dsn)
+		if err != nil {
+			return nil, err
+		}
+		driver := driverName
+		if src.driver != "" {
+			driver = src.driver
+		}
+		sqlxdb, err := db.openNode(src.name, driver, src.dsn)
+		if err != nil {
+			return nil, err
 		}
 
-		db.stats[i] = status
-		db.activedb = append(db.activedb, i)
+		n := newNode(i, src.name, sqlxdb)
+		n.setDriver(driver)
+		n.setDSN(src.dsn)
+		n.address = addr
+		n.tags = src.tags
+		for k, v := range db.opts.tags[src.name] {
+			if n.tags == nil {
+				n.tags = make(map[string]string)
+			}
+			n.tags[k] = v
+		}
+		n.label = src.label
+		if label, ok := db.opts.labels[src.name]; ok {
+			n.label = label
+		}
+		n.weight = src.weight
+		if lb, ok := db.opts.loadBalancers[src.name]; ok && lb.Weight > src.weight {
+			n.weight = lb.Weight
+		}
+		n.pingInterval = defaultHeartbeatInterval
+		if interval, ok := db.opts.pingIntervals[src.name]; ok {
+			n.pingInterval = interval
+		}
+		if pool, ok := db.opts.pools[src.name]; ok {
+			pool.apply(n)
+		}
+		db.applyLimit(n)
+		db.appendNode(n)
 	}
 
 	// set the default group name
@@ -60,244 +93,337 @@ func open(ctx context.Context, driverName, sources string, groupName string) (*D
 	if groupName != "" {
 		db.groupName = groupName
 	}
-	return db, err
+	db.readLabel, db.writeLabel = db.opts.readLabel, db.opts.writeLabel
+	master, err := db.labeledMaster(db.writeLabel)
+	if err != nil {
+		return nil, err
+	}
+	if len(db.opts.masters) > 0 {
+		if master, err = db.priorityMaster(); err != nil {
+			return nil, err
+		}
+	}
+	db.masterIndex = master
+	// every commented query would be prepared as its own statement
+	if db.opts.stmtCacheSize > 0 && !db.opts.queryComments {
+		db.stmtCache = newStmtCache(db.opts.stmtCacheSize, len(db.nodeList()))
+	}
+	db.updateRouting()
+
+	if d := db.opts.discovery; d != nil {
+		db.refreshDiscovery(ctx, d)
+	}
+	if d := db.opts.replicaDiscovery; d != nil {
+		db.refreshReplicas(ctx, d)
+	}
+	return db, nil
 }
 
-func openContextConnection(ctx context.Context, driverName, sources string, groupName string) (*DB, error) {
+func openContextConnection(ctx context.Context, driverName, sources string, groupName string, opts []Option) (*DB, error) {
 	// ping database to retrieve error
-	db, err := open(ctx, driverName, sources, groupName)
+	db, err := open(ctx, driverName, sources, groupName, opts)
 	if err != nil {
 		return nil, err
 	}
-	return db, db.PingContext(ctx)
+	// the discovery is only started once the open succeed, the DB returned with error is usually dropped
+	if err := db.tolerate(db.openPing(ctx)); err != nil {
+		return db, err
+	}
+	db.startDiscovery()
+	return db, nil
+}
+
+// WithOpenRetry ping the nodes again with exponential backoff when Open fail to reach them,
+// e.g. when the application start before the database accept connections. Attempts include the first ping
+func WithOpenRetry(attempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.openRetry = RetryPolicy{
+			MaxAttempts: attempts,
+			Backoff:     backoff,
+			MaxBackoff:  time.Second * 30,
+			Retryable:   func(error) bool { return true },
+		}
+	}
+}
+
+// openPing ping every node when opening, following the open retry
+func (db *DB) openPing(ctx context.Context) error {
+	policy := db.opts.openRetry
+	policy.OnRetry = func(attempt int, err error) {
+		db.opts.logger.Printf("sqlt: open attempt %d failed: %v", attempt, err)
+	}
+	return db.retry(ctx, policy, func() error {
+		return db.PingContext(ctx)
+	})
 }
 
 // OpenWithContext opening connection with context
-func OpenWithContext(ctx context.Context, driver, sources string) (*DB, error) {
-	return openContextConnection(ctx, driver, sources, "")
+func OpenWithContext(ctx context.Context, driver, sources string, opts ...Option) (*DB, error) {
+	return openContextConnection(ctx, driver, sources, "", opts)
 }
 
-// PingContext database
+// PingContext database, every node is pinged concurrently with its own timeout
+// and all node errors are returned joined together
 func (db *DB) PingContext(ctx context.Context) error {
-	var err error
+	return db.ping(ctx, db.members())
+}
 
-	if !db.heartBeat {
-		for _, val := range db.sqlxdb {
-			err = val.PingContext(ctx)
-			if err != nil {
-				return err
-			}
+// ping the given nodes and apply the results
+func (db *DB) ping(ctx context.Context, nodes []*node) error {
+	checks := db.pingNodes(ctx, nodes)
+
+	db.mu.Lock()
+	for n, check := range checks {
+		db.setHealth(n, check)
+	}
+	db.elect(checks)
+	db.failover()
+	db.updateRouting()
+	db.mu.Unlock()
+
+	var errs []error
+	for _, n := range nodes {
+		if err := checks[n].err; err != nil {
+			errs = append(errs, wrapError(err, n, "Ping", ""))
 		}
-		return err
 	}
+	return errors.Join(errs...)
+}
 
-	for i := 0; i < len(db.activedb); i++ {
-		val := db.activedb[i]
-		err = db.sqlxdb[val].PingContext(ctx)
-		name := db.stats[val].Name
-
-		if err != nil {
-			if db.length <= 1 {
-				return err
+// pingNodes ping every node concurrently, each attempt is bounded by its own timeout
+// so a black-holed host can't stall the heartbeat and delay the health of other nodes.
+// Inactive nodes are re-resolved before the ping so they can recover from address change
+func (db *DB) pingNodes(ctx context.Context, nodes []*node) map[*node]healthCheck {
+	active := make([]bool, len(nodes))
+	db.mu.Lock()
+	for i, n := range nodes {
+		active[i] = n.active
+	}
+	db.mu.Unlock()
+
+	checks := make([]healthCheck, len(nodes))
+	wg := sync.WaitGroup{}
+
+	for i, n := range nodes {
+		wg.Add(1)
+		go func(i int, n *node) {
+			defer wg.Done()
+			timeout := db.opts.pingTimeout
+			if !active[i] {
+				timeout = db.opts.reconnectTimeout
 			}
-
-			db.stats[val].Connected = false
-			db.activedb = append(db.activedb[:i], db.activedb[i+1:]...)
-			i--
-			db.inactivedb = append(db.inactivedb, val)
-			db.stats[val].Error = errors.New(name + ": " + err.Error())
-			dbLengthMutex.Lock()
-			db.length--
-			dbLengthMutex.Unlock()
-		} else {
-			db.stats[val].Connected = true
-			db.stats[val].LastActive = time.Now().Format(time.RFC1123)
-			db.stats[val].Error = nil
-		}
+			attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			if !active[i] {
+				if err := db.reresolve(attemptCtx, n); err != nil {
+					checks[i] = healthCheck{err: err}
+					return
+				}
+			}
+			checks[i] = db.pingNode(attemptCtx, n)
+		}(i, n)
 	}
+	wg.Wait()
 
-	for i := 0; i < len(db.inactivedb); i++ {
-		val := db.inactivedb[i]
-		err = db.sqlxdb[val].PingContext(ctx)
-		name := db.stats[val].Name
-
-		if err != nil {
-			db.stats[val].Connected = false
-			db.stats[val].Error = errors.New(name + ": " + err.Error())
-		} else {
-			db.stats[val].Connected = true
-			db.inactivedb = append(db.inactivedb[:i], db.inactivedb[i+1:]...)
-			i--
-			db.activedb = append(db.activedb, val)
-			db.stats[val].LastActive = time.Now().Format(time.RFC1123)
-			db.stats[val].Error = nil
-			dbLengthMutex.Lock()
-			db.length++
-			dbLengthMutex.Unlock()
-		}
+	result := make(map[*node]healthCheck, len(nodes))
+	for i, n := range nodes {
+		result[n] = checks[i]
 	}
-	return err
+	return result
 }
 
 // SelectContext using slave db.
 func (db *DB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return db.sqlxdb[db.slave()].SelectContext(ctx, dest, query, args...)
+	return db.runRead(ctx, call{op: "Select", query: query, args: args, dest: dest}, func(ctx context.Context, n *node, query string, dest interface{}) error {
+		q, done := db.queryer(ctx, n, query)
+		defer done()
+		return q.SelectContext(ctx, dest, query, args...)
+	})
 }
 
 // SelectMasterContext using master db.
 func (db *DB) SelectMasterContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return db.sqlxdb[0].SelectContext(ctx, dest, query, args...)
+	return db.runRead(ctx, call{op: "SelectMaster", query: query, args: args, dest: dest, master: true}, func(ctx context.Context, n *node, query string, dest interface{}) error {
+		q, done := db.queryer(ctx, n, query)
+		defer done()
+		return q.SelectContext(ctx, dest, query, args...)
+	})
 }
 
 // GetContext using slave.
 func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return db.sqlxdb[db.slave()].GetContext(ctx, dest, query, args...)
+	return db.runRead(ctx, call{op: "Get", query: query, args: args, dest: dest}, func(ctx context.Context, n *node, query string, dest interface{}) error {
+		q, done := db.queryer(ctx, n, query)
+		defer done()
+		return q.GetContext(ctx, dest, query, args...)
+	})
 }
 
 // GetMasterContext using master.
 func (db *DB) GetMasterContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return db.sqlxdb[0].GetContext(ctx, dest, query, args...)
-}
-
-// PrepareContext return sql stmt
-func (db *DB) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
-	var err error
-	stmt := new(Stmt)
-	stmts := make([]*sql.Stmt, len(db.sqlxdb))
-
-	for i := range db.sqlxdb {
-		stmts[i], err = db.sqlxdb[i].PrepareContext(ctx, query)
-
-		if err != nil {
-			return nil, err
-		}
-	}
-	stmt.db = db
-	stmt.stmts = stmts
-	return stmt, nil
-}
-
-// PreparexContext sqlx stmt
-func (db *DB) PreparexContext(ctx context.Context, query string) (*Stmtx, error) {
-	var err error
-	stmts := make([]*sqlx.Stmt, len(db.sqlxdb))
-
-	for i := range db.sqlxdb {
-		stmts[i], err = db.sqlxdb[i].PreparexContext(ctx, query)
-
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return &Stmtx{db: db, stmts: stmts}, nil
+	return db.runRead(ctx, call{op: "GetMaster", query: query, args: args, dest: dest, master: true}, func(ctx context.Context, n *node, query string, dest interface{}) error {
+		q, done := db.queryer(ctx, n, query)
+		defer done()
+		return q.GetContext(ctx, dest, query, args...)
+	})
 }
 
 // QueryContext queries the database and returns an *sql.Rows.
 func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	r, err := db.sqlxdb[db.slave()].QueryContext(ctx, query, args...)
-	return r, err
+	var rows *sql.Rows
+	c := call{op: "Query", query: query, args: args, stream: true}
+	err := db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+		q, done := db.queryer(ctx, n, query)
+		defer done()
+		var err error
+		rows, err = q.QueryContext(ctx, query, args...)
+		db.leaks.track(c, n, rows)
+		return err
+	})
+	return rows, err
 }
 
 // QueryRowContext queries the database and returns an *sqlx.Row.
 func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	rows := db.sqlxdb[db.slave()].QueryRowContext(ctx, query, args...)
-	return rows
+	var row *sql.Row
+	db.run(ctx, call{op: "QueryRow", query: query, args: args, stream: true}, func(ctx context.Context, n *node, query string) error {
+		q, done := db.queryer(ctx, n, query)
+		defer done()
+		row = q.QueryRowContext(ctx, query, args...)
+		return row.Err()
+	})
+	return row
 }
 
 // QueryxContext queries the database and returns an *sqlx.Rows.
 func (db *DB) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
-	r, err := db.sqlxdb[db.slave()].QueryxContext(ctx, query, args...)
-	return r, err
+	return db.queryx(ctx, call{op: "Queryx", query: query, args: args})
+}
+
+// QueryxMasterContext queries master and returns an *sqlx.Rows.
+func (db *DB) QueryxMasterContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	return db.queryx(ctx, call{op: "QueryxMaster", query: query, args: args, master: true})
+}
+
+func (db *DB) queryx(ctx context.Context, c call) (*sqlx.Rows, error) {
+	c.stream = true
+	var rows *sqlx.Rows
+	err := db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+		q, done := db.queryer(ctx, n, query)
+		defer done()
+		var err error
+		rows, err = q.QueryxContext(ctx, query, c.args...)
+		if err == nil {
+			db.leaks.track(c, n, rows.Rows)
+		}
+		return err
+	})
+	return rows, err
 }
 
 // QueryRowxContext queries the database and returns an *sqlx.Row.
 func (db *DB) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
-	rows := db.sqlxdb[db.slave()].QueryRowxContext(ctx, query, args...)
-	return rows
+	var row *sqlx.Row
+	db.run(ctx, call{op: "QueryRowx", query: query, args: args, stream: true}, func(ctx context.Context, n *node, query string) error {
+		q, done := db.queryer(ctx, n, query)
+		defer done()
+		row = q.QueryRowxContext(ctx, query, args...)
+		return row.Err()
+	})
+	return row
 }
 
 // ExecContext using master db
 func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return db.sqlxdb[0].ExecContext(ctx, query, args...)
+	var result sql.Result
+	err := db.retry(ctx, db.opts.writeRetry, func() error {
+		return db.run(ctx, call{op: "Exec", query: query, args: args, write: true}, func(ctx context.Context, n *node, query string) error {
+			q, done := db.queryer(ctx, n, query)
+			defer done()
+			var err error
+			result, err = q.ExecContext(ctx, query, args...)
+			return err
+		})
+	})
+	recordAffected(ctx, result)
+	return result, err
 }
 
 // MustExecContext (panic) runs MustExec using master database.
 func (db *DB) MustExecContext(ctx context.Context, query string, args ...interface{}) sql.Result {
-	return db.sqlxdb[0].MustExecContext(ctx, query, args...)
-}
-
-// ExecContext will always go to production
-func (st *Stmtx) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
-	return st.stmts[0].ExecContext(ctx, args...)
-}
-
-// QueryContext will always go to slave
-func (st *Stmtx) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
-	return st.stmts[st.db.slave()].QueryContext(ctx, args...)
-}
-
-// QueryMasterContext will use master db
-func (st *Stmtx) QueryMasterContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
-	return st.stmts[0].QueryContext(ctx, args...)
-}
-
-// QueryRowContext will always go to slave
-func (st *Stmtx) QueryRowContext(ctx context.Context, args ...interface{}) *sql.Row {
-	return st.stmts[st.db.slave()].QueryRowContext(ctx, args...)
-}
-
-// QueryRowMasterContext will use master db
-func (st *Stmtx) QueryRowMasterContext(ctx context.Context, args ...interface{}) *sql.Row {
-	return st.stmts[0].QueryRowContext(ctx, args...)
-}
-
-// MustExecContext using master database
-func (st *Stmtx) MustExecContext(ctx context.Context, args ...interface{}) sql.Result {
-	return st.stmts[0].MustExecContext(ctx, args...)
-}
-
-// QueryxContext will always go to slave
-func (st *Stmtx) QueryxContext(ctx context.Context, args ...interface{}) (*sqlx.Rows, error) {
-	return st.stmts[st.db.slave()].QueryxContext(ctx, args...)
-}
-
-// QueryRowxContext will always go to slave
-func (st *Stmtx) QueryRowxContext(ctx context.Context, args ...interface{}) *sqlx.Row {
-	return st.stmts[st.db.slave()].QueryRowxContext(ctx, args...)
-}
-
-// QueryRowxMasterContext will always go to master
-func (st *Stmtx) QueryRowxMasterContext(ctx context.Context, args ...interface{}) *sqlx.Row {
-	return st.stmts[0].QueryRowxContext(ctx, args...)
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		panic(err)
+	}
+	return result
 }
 
-// GetContext will always go to slave
-func (st *Stmtx) GetContext(ctx context.Context, dest interface{}, args ...interface{}) error {
-	return st.stmts[st.db.slave()].GetContext(ctx, dest, args...)
+// NamedExecContext using master db.
+func (db *DB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := db.retry(ctx, db.opts.writeRetry, func() error {
+		return db.run(ctx, call{op: "NamedExec", query: query, args: []interface{}{arg}, write: true}, func(ctx context.Context, n *node, query string) error {
+			query, args, err := n.bindNamed(query, arg)
+			if err != nil {
+				return err
+			}
+			q, done := db.queryer(ctx, n, query)
+			defer done()
+			result, err = q.ExecContext(ctx, query, args...)
+			return err
+		})
+	})
+	recordAffected(ctx, result)
+	return result, err
 }
 
-// GetMasterContext will always go to master
-func (st *Stmtx) GetMasterContext(ctx context.Context, dest interface{}, args ...interface{}) error {
-	return st.stmts[0].GetContext(ctx, dest, args...)
+// BeginTx return sql.Tx
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	ctx = db.decorate(ctx)
+	if err := db.waitMaster(ctx); err != nil {
+		return nil, err
+	}
+	return db.Master().BeginTx(ctx, opts)
 }
 
-// SelectContext will always go to slave
-func (st *Stmtx) SelectContext(ctx context.Context, dest interface{}, args ...interface{}) error {
-	return st.stmts[st.db.slave()].SelectContext(ctx, dest, args...)
+// BeginTxx return Tx of master
+func (db *DB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	ctx = db.decorate(ctx)
+	if err := db.waitMaster(ctx); err != nil {
+		return nil, err
+	}
+	n := db.nodeAt(db.master())
+	tx, err := n.db().BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, db: db, n: n, ctx: ctx}, nil
 }
 
-// SelectMasterContext will always go to master
-func (st *Stmtx) SelectMasterContext(ctx context.Context, dest interface{}, args ...interface{}) error {
-	return st.stmts[0].SelectContext(ctx, dest, args...)
+// MustBeginTx (panic) return Tx of master
+func (db *DB) MustBeginTx(ctx context.Context, opts *sql.TxOptions) *Tx {
+	tx, err := db.BeginTxx(ctx, opts)
+	if err != nil {
+		panic(err)
+	}
+	return tx
 }
 
-// BeginTx return sql.Tx
-func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
-	return db.Master().BeginTx(ctx, opts)
+// Conn return a single connection of master, e.g. for advisory locks or temporary tables.
+// The connection must be closed to return it to the pool
+func (db *DB) Conn(ctx context.Context) (*sql.Conn, error) {
+	if err := db.waitMaster(ctx); err != nil {
+		return nil, err
+	}
+	return db.Master().Conn(ctx)
 }
 
-// BeginTxx return sqlx.Tx
-func (db *DB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
-	return db.Master().BeginTxx(ctx, opts)
+// SlaveConn return a single connection of the slave serving the next read,
+// the connection must be closed to return it to the pool
+func (db *DB) SlaveConn(ctx context.Context) (*sql.Conn, error) {
+	n, err := db.pick(ctx, call{})
+	if err != nil {
+		return nil, err
+	}
+	return n.db().Conn(ctx)
 }
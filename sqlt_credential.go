@@ -0,0 +1,151 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// maxCredentialRefreshMargin bound how early an expiring credential is refreshed
+const maxCredentialRefreshMargin = time.Minute
+
+// Credential is the DSN used to connect until it expire, zero Expiry never expire
+type Credential struct {
+	DSN    string
+	Expiry time.Time
+}
+
+// CredentialProvider return a fresh credential of the node, e.g. an RDS IAM token or Vault dynamic
+// credentials. dsn is the configured DSN of the node
+type CredentialProvider interface {
+	Credential(ctx context.Context, nodeName, dsn string) (Credential, error)
+}
+
+// CredentialFunc is a function implementing CredentialProvider
+type CredentialFunc func(ctx context.Context, nodeName, dsn string) (Credential, error)
+
+// Credential call fn
+func (fn CredentialFunc) Credential(ctx context.Context, nodeName, dsn string) (Credential, error) {
+	return fn(ctx, nodeName, dsn)
+}
+
+// WithCredentialProvider connect every node with the credential returned by p instead of the static DSN.
+// The credential is cached until halfway to its expiry, the connection lifetime of the pool is capped
+// so connections opened with an expiring credential are recycled before the expiry
+func WithCredentialProvider(p CredentialProvider) Option {
+	return func(o *options) {
+		o.credentials = p
+	}
+}
+
+// credentialPools map the pool of a node connecting with a credential provider to its connector,
+// the connector is removed once the pool is closed
+var credentialPools sync.Map
+
+// credentialConnector connect with the current credential of the node. The connections are not wrapped,
+// the pool recycle them through its connection lifetime instead: an expiring credential is refreshed
+// halfway to its expiry and connections live at most half of it, so every connection is closed
+// before the credential it was opened with expire
+type credentialConnector struct {
+	name     string
+	dsn      string
+	provider CredentialProvider
+	driver   driver.Driver
+
+	mu        sync.Mutex
+	connector driver.Connector
+	refreshAt time.Time
+	pool      *sql.DB
+	// lifetime of the connections opened with the current credential, zero never expire
+	lifetime time.Duration
+	// maxLifetime is the connection lifetime configured on the pool
+	maxLifetime time.Duration
+}
+
+// current return the connector of the current credential, fetching a new one when it is due
+func (c *credentialConnector) current(ctx context.Context) (driver.Connector, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.connector != nil && (c.refreshAt.IsZero() || time.Now().Before(c.refreshAt)) {
+		return c.connector, nil
+	}
+
+	cred, err := c.provider.Credential(ctx, c.name, c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := newConnector(c.driver, cred.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	c.connector, c.refreshAt, c.lifetime = connector, time.Time{}, 0
+	if !cred.Expiry.IsZero() {
+		valid := time.Until(cred.Expiry)
+		margin := valid / 10
+		if margin > maxCredentialRefreshMargin {
+			margin = maxCredentialRefreshMargin
+		}
+		// zero lifetime never expire the connections
+		c.lifetime = max((valid-margin)/2, time.Millisecond)
+		c.refreshAt = time.Now().Add(c.lifetime)
+	}
+	c.applyLifetime()
+	return c.connector, nil
+}
+
+// setMaxLifetime set the connection lifetime configured on the pool, capped by the credential lifetime
+func (c *credentialConnector) setMaxLifetime(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxLifetime = d
+	c.applyLifetime()
+}
+
+// applyLifetime set the shorter of the configured and the credential lifetime on the pool, must be called with mu held
+func (c *credentialConnector) applyLifetime() {
+	if c.pool == nil {
+		return
+	}
+	d := c.maxLifetime
+	if c.lifetime > 0 && (d <= 0 || c.lifetime < d) {
+		d = c.lifetime
+	}
+	c.pool.SetConnMaxLifetime(d)
+}
+
+func (c *credentialConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	connector, err := c.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c *credentialConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Close is called when the pool is closed
+func (c *credentialConnector) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pool != nil {
+		credentialPools.Delete(c.pool)
+	}
+	return nil
+}
+
+// setConnMaxLifetime set the connection lifetime of the node pool
+func setConnMaxLifetime(pool *sqlx.DB, d time.Duration) {
+	if c, ok := credentialPools.Load(pool.DB); ok {
+		c.(*credentialConnector).setMaxLifetime(d)
+		return
+	}
+	pool.SetConnMaxLifetime(d)
+}
@@ -0,0 +1,72 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithNodeLabel label the node, e.g. "blue" or "green" for blue/green migrations.
+// Label can also be set in the source, e.g. "slave-1=dsn,label=green"
+func WithNodeLabel(name, label string) Option {
+	return func(o *options) {
+		o.labels[name] = label
+	}
+}
+
+// WithServingLabels only route reads to nodes labeled reads and writes to the first node labeled writes,
+// empty label means every node
+func WithServingLabels(reads, writes string) Option {
+	return func(o *options) {
+		o.readLabel = reads
+		o.writeLabel = writes
+	}
+}
+
+// CutoverHook validate the cutover before the switch happen, returning error abort the cutover
+type CutoverHook func(ctx context.Context, db *DB, reads, writes string) error
+
+// Cutover atomically switch the labels serving reads and writes, the master become the first
+// node labeled writes. Every hook must pass before the switch happen
+func (db *DB) Cutover(ctx context.Context, reads, writes string, hooks ...CutoverHook) error {
+	master, err := db.labeledMaster(writes)
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if err := hook(ctx, db, reads, writes); err != nil {
+			return fmt.Errorf("cutover to %s/%s aborted: %w", reads, writes, err)
+		}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.readLabel = reads
+	db.writeLabel = writes
+	if master != db.masterIndex {
+		db.recordEvent(db.nodeAt(master), EventMaster, nil)
+	}
+	db.masterIndex = master
+	db.updateRouting()
+	db.opts.logger.Printf("sqlt: cutover reads to %q and writes to %q", reads, writes)
+	return nil
+}
+
+// ServingLabels return the labels currently serving reads and writes
+func (db *DB) ServingLabels() (reads, writes string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.readLabel, db.writeLabel
+}
+
+// labeledMaster return the index of the first node with the label
+func (db *DB) labeledMaster(label string) (int, error) {
+	if label == "" {
+		return 0, nil
+	}
+	for _, n := range db.nodeList() {
+		if n.label == label {
+			return n.index, nil
+		}
+	}
+	return 0, fmt.Errorf("no node labeled %q: %w", label, ErrNodeNotFound)
+}
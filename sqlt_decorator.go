@@ -0,0 +1,22 @@
+package sqlt
+
+import "context"
+
+// WithContextDecorator apply decorate to the context of every call before it is routed, e.g. to add
+// comment tags or trace attributes in one place for every service. Decorators are applied in the
+// order they are given. The decorated context can't be canceled, the base timeout of the queries
+// is set with WithDefaultQueryTimeout instead
+func WithContextDecorator(decorate func(ctx context.Context) context.Context) Option {
+	return func(o *options) {
+		o.decorators = append(o.decorators, decorate)
+		o.pipeline = true
+	}
+}
+
+// decorate return ctx with the context decorators applied
+func (db *DB) decorate(ctx context.Context) context.Context {
+	for _, decorate := range db.opts.decorators {
+		ctx = decorate(ctx)
+	}
+	return ctx
+}
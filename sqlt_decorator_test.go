@@ -0,0 +1,63 @@
+package sqlt
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextDecorator(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(ctx context.Context, db *DB) error
+	}{
+		{
+			name: "read",
+			call: func(ctx context.Context, db *DB) error {
+				var dsn string
+				return db.GetContext(ctx, &dsn, "SELECT dsn")
+			},
+		},
+		{
+			name: "write",
+			call: func(ctx context.Context, db *DB) error {
+				_, err := db.ExecContext(ctx, "UPDATE users SET name = ''")
+				return err
+			},
+		},
+		{
+			name: "BeginTx",
+			call: func(ctx context.Context, db *DB) error {
+				tx, err := db.BeginTx(ctx, nil)
+				if err != nil {
+					return err
+				}
+				return tx.Rollback()
+			},
+		},
+		{
+			name: "BeginTxx",
+			call: func(ctx context.Context, db *DB) error {
+				tx, err := db.BeginTxx(ctx, nil)
+				if err != nil {
+					return err
+				}
+				return tx.Rollback()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decorated := 0
+			db := newMockDB(t, 1, WithContextDecorator(func(ctx context.Context) context.Context {
+				decorated++
+				return ctx
+			}))
+			if err := tt.call(context.Background(), db); err != nil {
+				t.Fatal(err)
+			}
+			if decorated == 0 {
+				t.Fatal("context not decorated")
+			}
+		})
+	}
+}
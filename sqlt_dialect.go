@@ -0,0 +1,128 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Dialect is the database family of a driver, used for bindvars and driver specific queries
+type Dialect string
+
+// supported dialects, DialectUnknown use the postgres queries
+const (
+	DialectUnknown   Dialect = ""
+	DialectPostgres  Dialect = "postgres"
+	DialectMySQL     Dialect = "mysql"
+	DialectSQLite    Dialect = "sqlite"
+	DialectSQLServer Dialect = "sqlserver"
+)
+
+// dialectDrivers map the registered driver names to their dialect
+var dialectDrivers = map[string]Dialect{
+	"postgres":         DialectPostgres,
+	"pgx":              DialectPostgres,
+	"pgx/v5":           DialectPostgres,
+	"cloudsqlpostgres": DialectPostgres,
+	"nrpostgres":       DialectPostgres,
+	"cockroach":        DialectPostgres,
+	"mysql":            DialectMySQL,
+	"nrmysql":          DialectMySQL,
+	"sqlite3":          DialectSQLite,
+	"sqlite":           DialectSQLite,
+	"nrsqlite3":        DialectSQLite,
+	"sqlserver":        DialectSQLServer,
+	"mssql":            DialectSQLServer,
+}
+
+// dialectPackages map the package of driver implementations to their dialect,
+// used for drivers registered under another name
+var dialectPackages = map[string]Dialect{
+	"github.com/jackc/pgx":             DialectPostgres,
+	"github.com/lib/pq":                DialectPostgres,
+	"github.com/go-sql-driver/mysql":   DialectMySQL,
+	"github.com/mattn/go-sqlite3":      DialectSQLite,
+	"modernc.org/sqlite":               DialectSQLite,
+	"github.com/denisenkom/go-mssqldb": DialectSQLServer,
+	"github.com/microsoft/go-mssqldb":  DialectSQLServer,
+}
+
+// detectDialect return the dialect of the driver name, or of the driver implementation
+// when the name is unknown. drv can be nil
+func detectDialect(driverName string, drv driver.Driver) Dialect {
+	if d, ok := dialectDrivers[driverName]; ok {
+		return d
+	}
+	if drv == nil {
+		return DialectUnknown
+	}
+	t := reflect.TypeOf(drv)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for pkg, d := range dialectPackages {
+		if strings.HasPrefix(t.PkgPath(), pkg) {
+			return d
+		}
+	}
+	return DialectUnknown
+}
+
+// bindType return the bindvar type of the dialect
+func (d Dialect) bindType() int {
+	switch d {
+	case DialectPostgres:
+		return sqlx.DOLLAR
+	case DialectMySQL, DialectSQLite:
+		return sqlx.QUESTION
+	case DialectSQLServer:
+		return sqlx.AT
+	}
+	return sqlx.UNKNOWN
+}
+
+// setDriver set the driver of the node and detect its dialect, driver names unknown to sqlx
+// use the bindvar of the dialect. The bindvar is kept on the node instead of registered to sqlx,
+// so it doesn't leak into other users of the driver name
+func (n *node) setDriver(driverName string) {
+	n.driver = driverName
+	n.dialect = detectDialect(driverName, n.db().Driver())
+	n.bind = sqlx.BindType(driverName)
+	if n.bind == sqlx.UNKNOWN {
+		n.bind = n.dialect.bindType()
+	}
+}
+
+// rebind convert the ? bindvars of the query to the bindvar of the node
+func (n *node) rebind(query string) string {
+	return sqlx.Rebind(n.bind, query)
+}
+
+// bindNamed bind the named query with arg using the bindvar of the node
+func (n *node) bindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	query, args, err := n.db().BindNamed(query, arg)
+	if err != nil || sqlx.BindType(n.driver) != sqlx.UNKNOWN {
+		return query, args, err
+	}
+	// sqlx bind the named query of unknown drivers with ?
+	return n.rebind(query), args, nil
+}
+
+// Dialect return the dialect detected from the master driver
+func (db *DB) Dialect() Dialect {
+	return db.nodeAt(db.master()).dialect
+}
+
+// RawMaster run fn with the driver connection of a master connection, e.g. the *stdlib.Conn of pgx
+// to LISTEN and wait for notifications. The connection is returned to the pool after fn return
+func (db *DB) RawMaster(ctx context.Context, fn func(driverConn interface{}) error) error {
+	conn, err := db.Master().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(fn)
+}
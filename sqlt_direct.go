@@ -0,0 +1,60 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ExecNode execute the query on the named node, e.g. to run maintenance on a specific slave
+func (db *DB) ExecNode(ctx context.Context, name, query string, args ...interface{}) (sql.Result, error) {
+	n, err := db.node(name)
+	if err != nil {
+		return nil, err
+	}
+	var result sql.Result
+	err = db.run(ctx, call{op: "ExecNode", query: query, args: args, target: n}, func(ctx context.Context, n *node, query string) error {
+		var err error
+		result, err = n.db().ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// QueryNode queries the named node and returns an *sql.Rows
+func (db *DB) QueryNode(ctx context.Context, name, query string, args ...interface{}) (*sql.Rows, error) {
+	n, err := db.node(name)
+	if err != nil {
+		return nil, err
+	}
+	var rows *sql.Rows
+	c := call{op: "QueryNode", query: query, args: args, stream: true, target: n}
+	err = db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+		var err error
+		rows, err = db.conn(ctx, n).QueryContext(ctx, query, args...)
+		db.leaks.track(c, n, rows)
+		return err
+	})
+	return rows, err
+}
+
+// GetNode get a single row from the named node
+func (db *DB) GetNode(ctx context.Context, name string, dest interface{}, query string, args ...interface{}) error {
+	n, err := db.node(name)
+	if err != nil {
+		return err
+	}
+	return db.run(ctx, call{op: "GetNode", query: query, args: args, dest: dest, target: n}, func(ctx context.Context, n *node, query string) error {
+		return n.db().GetContext(ctx, dest, query, args...)
+	})
+}
+
+// SelectNode select rows from the named node
+func (db *DB) SelectNode(ctx context.Context, name string, dest interface{}, query string, args ...interface{}) error {
+	n, err := db.node(name)
+	if err != nil {
+		return err
+	}
+	return db.run(ctx, call{op: "SelectNode", query: query, args: args, dest: dest, target: n}, func(ctx context.Context, n *node, query string) error {
+		return n.db().SelectContext(ctx, dest, query, args...)
+	})
+}
@@ -0,0 +1,303 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultDiscoveryInterval = time.Second * 30
+
+// SRVResolver lookup the SRV records of a service, *net.Resolver satisfy this interface
+type SRVResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// Discovery resolve a DNS name into slaves, e.g. a reader endpoint or a headless Kubernetes service
+type Discovery struct {
+	// Name is the resolved DNS name
+	Name string
+	// Service and Proto lookup the SRV records of "_service._proto.name" instead of the host addresses
+	Service string
+	Proto   string
+	// Port of the resolved host addresses, SRV records carry their own port
+	Port string
+	// DSN of every discovered slave, {addr} is replaced by host:port, {host} and {port} separately
+	DSN string
+	// Interval between refreshes, default to 30 seconds
+	Interval time.Duration
+	Weight   int
+	Tags     map[string]string
+	// Resolver default to opts resolver and net.DefaultResolver
+	Resolver Resolver
+	// SRVResolver default to net.DefaultResolver
+	SRVResolver SRVResolver
+}
+
+// WithDiscovery add and remove slaves following the addresses resolved from the DNS name.
+// Discovered slaves are named by their address and join the routing once pinged
+func WithDiscovery(d Discovery) Option {
+	return func(o *options) {
+		o.discovery = &d
+	}
+}
+
+// lookup return the discovered addresses sorted
+func (d *Discovery) lookup(ctx context.Context, fallback Resolver) ([]string, error) {
+	var addrs []string
+	if d.Service != "" {
+		var r SRVResolver = net.DefaultResolver
+		if d.SRVResolver != nil {
+			r = d.SRVResolver
+		}
+		_, records, err := r.LookupSRV(ctx, d.Service, d.Proto, d.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, srv := range records {
+			addrs = append(addrs, net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port))))
+		}
+	} else {
+		var r Resolver = net.DefaultResolver
+		if d.Resolver != nil {
+			r = d.Resolver
+		} else if fallback != nil {
+			r = fallback
+		}
+		hosts, err := r.LookupHost(ctx, d.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range hosts {
+			addrs = append(addrs, joinHostPort(host, d.Port))
+		}
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
+// discoveredDSN fill the DSN template with the slave address
+func discoveredDSN(template, addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return strings.NewReplacer("{addr}", addr, "{host}", host, "{port}", port).Replace(template)
+}
+
+// startDiscovery refresh the discovered slaves on the discovery intervals until the DB is closed
+func (db *DB) startDiscovery() {
+	if d := db.opts.discovery; d != nil {
+		db.discover(d.Interval, func(ctx context.Context) error { return db.refreshDiscovery(ctx, d) })
+	}
+	if d := db.opts.replicaDiscovery; d != nil {
+		db.discover(d.Interval, func(ctx context.Context) error { return db.refreshReplicas(ctx, d) })
+	}
+}
+
+// discover call refresh every interval until the DB is closed
+func (db *DB) discover(interval time.Duration, refresh func(ctx context.Context) error) {
+	if interval <= 0 {
+		interval = defaultDiscoveryInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), db.opts.reconnectTimeout)
+				refresh(ctx)
+				cancel()
+			case <-db.stopDiscovery:
+				return
+			}
+		}
+	}()
+}
+
+// refreshDiscovery resolve the discovery and sync the discovered slaves with the result,
+// a failed lookup keep the current slaves
+func (db *DB) refreshDiscovery(ctx context.Context, d *Discovery) error {
+	addrs, err := d.lookup(ctx, db.opts.resolver)
+	if err != nil {
+		db.opts.logger.Printf("sqlt: discovery of %s failed: %v", d.Name, err)
+		return err
+	}
+	desired := make(map[string]string, len(addrs))
+	for _, addr := range addrs {
+		desired[addr] = discoveredDSN(d.DSN, addr)
+	}
+	return db.syncReplicas(ctx, "dns", desired, d.Weight, d.Tags)
+}
+
+// syncReplicas remove the slaves discovered by source missing from desired and add the new ones,
+// desired map node name to DSN. Configured nodes and the master are never removed
+func (db *DB) syncReplicas(ctx context.Context, source string, desired map[string]string, weight int, tags map[string]string) error {
+	var stale []*node
+	db.mu.Lock()
+	master := db.masterIndex
+	for _, n := range db.nodeList() {
+		if _, ok := desired[n.name]; ok {
+			delete(desired, n.name)
+			continue
+		}
+		if n.discoveredBy == source && n.index != master {
+			n.removed.Store(true)
+			n.active = false
+			stale = append(stale, n)
+		}
+	}
+	if len(stale) > 0 {
+		db.removeNodes(stale)
+		db.updateRouting()
+	}
+	db.mu.Unlock()
+
+	for _, n := range stale {
+		db.opts.logger.Printf("sqlt: node %s removed by discovery", n.name)
+		db.recordEvent(n, EventRemoved, nil)
+		go db.retire(n)
+	}
+
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var added []*node
+	for _, name := range names {
+		addr, err := db.resolveAddress(ctx, desired[name])
+		if err != nil {
+			db.opts.logger.Printf("sqlt: discovered node %s: %v", name, err)
+			continue
+		}
+		conn, err := db.openNode(name, db.driverName, desired[name])
+		if err != nil {
+			db.opts.logger.Printf("sqlt: discovered node %s: %v", name, err)
+			continue
+		}
+
+		db.mu.Lock()
+		n := newNode(db.nextIndex, name, conn)
+		n.setDriver(db.driverName)
+		n.setDSN(desired[name])
+		n.address = addr
+		n.discoveredBy = source
+		n.active = false
+		n.status.Connected = false
+		n.tags = tags
+		n.label = db.opts.labels[name]
+		if weight > 0 {
+			n.weight = weight
+		}
+		n.pingInterval = defaultHeartbeatInterval
+		if pool, ok := db.opts.pools[name]; ok {
+			pool.apply(n)
+		}
+		db.applyLimit(n)
+		db.appendNode(n)
+		db.mu.Unlock()
+		added = append(added, n)
+		db.opts.logger.Printf("sqlt: node %s added by discovery", name)
+		db.recordEvent(n, EventAdded, nil)
+	}
+
+	if len(added) == 0 {
+		return nil
+	}
+	return db.ping(ctx, added)
+}
+
+// ReplicaDiscovery query the master for its replicas, so only the master DSN need to be configured
+type ReplicaDiscovery struct {
+	// Query return the replicas, the host is read from the host or client_addr column and
+	// the port from the port column. Default to pg_stat_replication for postgres and
+	// "SHOW REPLICAS" for mysql
+	Query string
+	// Port of the replicas when the query doesn't return it
+	Port string
+	// DSN of every discovered slave, {addr} is replaced by host:port, {host} and {port} separately
+	DSN string
+	// Interval between refreshes, default to 30 seconds
+	Interval time.Duration
+	Weight   int
+	Tags     map[string]string
+}
+
+// WithReplicaDiscovery add and remove slaves following the replicas reported by the master
+func WithReplicaDiscovery(d ReplicaDiscovery) Option {
+	return func(o *options) {
+		o.replicaDiscovery = &d
+	}
+}
+
+func (db *DB) replicasQuery(d *ReplicaDiscovery, n *node) string {
+	if d.Query != "" {
+		return d.Query
+	}
+	if n.dialect == DialectMySQL {
+		return "SHOW REPLICAS"
+	}
+	return "SELECT host(client_addr) AS host FROM pg_stat_replication WHERE client_addr IS NOT NULL"
+}
+
+// replicas query the master for the address of its replicas
+func (db *DB) replicas(ctx context.Context, d *ReplicaDiscovery) ([]string, error) {
+	master := db.nodeAt(db.master())
+	rows, err := master.db().QueryxContext(ctx, db.replicasQuery(d, master))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addrs []string
+	for rows.Next() {
+		row := make(map[string]interface{})
+		if err := rows.MapScan(row); err != nil {
+			return nil, err
+		}
+		host, port := "", d.Port
+		for column, value := range row {
+			var s string
+			switch v := value.(type) {
+			case []byte:
+				s = string(v)
+			case nil:
+				continue
+			default:
+				s = fmt.Sprint(v)
+			}
+			switch strings.ToLower(column) {
+			case "host", "client_addr":
+				host = s
+			case "port":
+				port = s
+			}
+		}
+		if host != "" {
+			addrs = append(addrs, joinHostPort(host, port))
+		}
+	}
+	return addrs, rows.Err()
+}
+
+// refreshReplicas sync the discovered slaves with the replicas reported by the master,
+// a failed query keep the current slaves
+func (db *DB) refreshReplicas(ctx context.Context, d *ReplicaDiscovery) error {
+	addrs, err := db.replicas(ctx, d)
+	if err != nil {
+		db.opts.logger.Printf("sqlt: replica discovery failed: %v", err)
+		return err
+	}
+	desired := make(map[string]string, len(addrs))
+	for _, addr := range addrs {
+		desired[addr] = discoveredDSN(d.DSN, addr)
+	}
+	return db.syncReplicas(ctx, "master", desired, d.Weight, d.Tags)
+}
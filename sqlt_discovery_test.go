@@ -0,0 +1,115 @@
+package sqlt
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// staticResolver resolve every host to the addresses set by the test
+type staticResolver struct {
+	mu    sync.Mutex
+	addrs []string
+}
+
+func (r *staticResolver) set(addrs ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addrs = addrs
+}
+
+func (r *staticResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.addrs), nil
+}
+
+// slaveNames return the names of the slaves serving reads, sorted
+func slaveNames(db *DB) []string {
+	var names []string
+	for _, n := range db.route.Load().slaves {
+		names = append(names, n.name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestDiscoveryAddRemove(t *testing.T) {
+	resolver := &staticResolver{}
+	db := newMockDB(t, 1, WithDiscovery(Discovery{Name: "replicas.local", Port: "5432", DSN: "{host}", Resolver: resolver}))
+	d := db.opts.discovery
+
+	tests := []struct {
+		name     string
+		resolved []string
+		want     []string
+	}{
+		{name: "slaves added", resolved: []string{"10.0.0.2", "10.0.0.1"}, want: []string{"10.0.0.1:5432", "10.0.0.2:5432", "slave-1"}},
+		{name: "unchanged", resolved: []string{"10.0.0.1", "10.0.0.2"}, want: []string{"10.0.0.1:5432", "10.0.0.2:5432", "slave-1"}},
+		{name: "slave replaced", resolved: []string{"10.0.0.2", "10.0.0.3"}, want: []string{"10.0.0.2:5432", "10.0.0.3:5432", "slave-1"}},
+		{name: "every discovered slave removed", resolved: nil, want: []string{"slave-1"}},
+		{name: "slave added again", resolved: []string{"10.0.0.1"}, want: []string{"10.0.0.1:5432", "slave-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver.set(tt.resolved...)
+			if err := db.refreshDiscovery(context.Background(), d); err != nil {
+				t.Fatal(err)
+			}
+			if got := slaveNames(db); !slices.Equal(got, tt.want) {
+				t.Fatalf("slaves %v, want %v", got, tt.want)
+			}
+
+			// removed slaves are dropped from the node list and every read is served by the discovered slaves
+			if got := len(db.nodeList()); got != len(tt.want)+1 {
+				t.Fatalf("%d nodes, want %d", got, len(tt.want)+1)
+			}
+			for i := 0; i < len(tt.want)*2; i++ {
+				host := readNode(t, db)
+				if host != "slave-1" && !slices.Contains(tt.resolved, host) {
+					t.Fatalf("read served by %s", host)
+				}
+			}
+		})
+	}
+}
+
+func TestDiscoveryRemovedNodeClosed(t *testing.T) {
+	resolver := &staticResolver{}
+	resolver.set("10.0.0.1")
+	db := newMockDB(t, 1, WithDiscovery(Discovery{Name: "replicas.local", Port: "5432", DSN: "{host}", Resolver: resolver}))
+	if err := db.refreshDiscovery(context.Background(), db.opts.discovery); err != nil {
+		t.Fatal(err)
+	}
+	n, err := db.node("10.0.0.1:5432")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a streamed read keep the removed node open until its rows are closed
+	rows, err := db.QueryNode(context.Background(), n.name, "SELECT dsn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver.set()
+	if err := db.refreshDiscovery(context.Background(), db.opts.discovery); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.node(n.name); err == nil {
+		t.Fatal("removed node still found")
+	}
+	if err := n.db().PingContext(context.Background()); err != nil {
+		t.Fatalf("removed node closed with rows open: %v", err)
+	}
+
+	rows.Close()
+	deadline := time.Now().Add(time.Second)
+	for n.db().PingContext(context.Background()) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("removed node not closed once its rows are closed")
+		}
+		time.Sleep(retireInterval)
+	}
+}
@@ -0,0 +1,30 @@
+package sqlt
+
+// mixedBind return true when the nodes don't share the same bindvar, e.g. a pgx node
+// inside a mysql group
+func (db *DB) mixedBind() bool {
+	if r := db.route.Load(); r != nil {
+		return r.mixedBind
+	}
+	return db.bindvarsDiffer()
+}
+
+// bindvarsDiffer compare the bindvar of every node with the bindvar of the group driver
+func (db *DB) bindvarsDiffer() bool {
+	bindType := db.BindType()
+	for _, n := range db.members() {
+		if n.bind != bindType {
+			return true
+		}
+	}
+	return false
+}
+
+// rebind convert the ? bindvars of the query to the bindvar of the node,
+// only done when the nodes don't share the same bindvar
+func (db *DB) rebind(n *node, query string) string {
+	if !db.mixedBind() {
+		return query
+	}
+	return n.rebind(query)
+}
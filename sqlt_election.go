@@ -0,0 +1,82 @@
+package sqlt
+
+import (
+	"context"
+	"strings"
+)
+
+// MasterElection detect which node is writable and promote it as master, the old master
+// is demoted into the slave rotation. Election run on every Ping, including heartbeat
+type MasterElection struct {
+	// WritableQuery return true or 1 when the node accept writes, default to
+	// "SELECT NOT pg_is_in_recovery()" for postgres and "SELECT @@read_only = 0" for mysql
+	WritableQuery string
+}
+
+// WithMasterElection enable automatic writable master detection and failover
+func WithMasterElection(election MasterElection) Option {
+	return func(o *options) {
+		o.election = &election
+	}
+}
+
+func (db *DB) writableQuery(n *node) string {
+	if q := db.opts.election.WritableQuery; q != "" {
+		return q
+	}
+	if n.dialect == DialectMySQL {
+		return "SELECT @@read_only = 0"
+	}
+	return "SELECT NOT pg_is_in_recovery()"
+}
+
+// checkWritable report whether the node accept writes
+func (db *DB) checkWritable(ctx context.Context, n *node) bool {
+	var result string
+	if err := n.db().QueryRowContext(ctx, db.writableQuery(n)).Scan(&result); err != nil {
+		return false
+	}
+	return truthy(result)
+}
+
+// truthy report whether the scanned boolean result is true
+func truthy(result string) bool {
+	result = strings.ToLower(result)
+	return result == "true" || result == "1" || result == "t"
+}
+
+// elect promote a writable node when the current master is not writable anymore,
+// must be called with DB.mu held
+func (db *DB) elect(checks map[*node]healthCheck) {
+	if db.opts.election == nil || len(db.opts.masters) > 0 {
+		return
+	}
+
+	current := db.nodeAt(db.masterIndex)
+	check, ok := checks[current]
+	if !ok {
+		// master is not checked in this round
+		return
+	}
+	if check.err == nil && check.writable {
+		db.masterLost = false
+		return
+	}
+
+	for _, n := range db.members() {
+		if n.disabled || (db.writeLabel != "" && n.label != db.writeLabel) {
+			continue
+		}
+		if check, ok := checks[n]; ok && check.err == nil && check.writable {
+			db.opts.logger.Printf("sqlt: master changed from %s to %s", current.name, n.name)
+			db.recordEvent(n, EventMaster, nil)
+			db.masterIndex = n.index
+			db.masterLost = false
+			return
+		}
+	}
+	if !db.masterLost {
+		db.opts.logger.Printf("sqlt: no writable master, %s is not writable", current.name)
+	}
+	db.masterLost = true
+}
@@ -0,0 +1,37 @@
+package sqlt
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Error is returned by queries routed by the DB, it records which node failed.
+// Use errors.As to retrieve it and errors.Is to compare the underlying error
+type Error struct {
+	Node  string
+	Op    string
+	Query string
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return "sqlt: " + e.Op + " on " + e.Node + ": " + e.Err.Error()
+}
+
+// Unwrap return the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// wrapError attribute the error to the node, sql.ErrNoRows is returned as is
+// so the common err == sql.ErrNoRows comparison keep working
+func wrapError(err error, n *node, op, query string) error {
+	if err == nil || err == sql.ErrNoRows {
+		return err
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Node: n.name, Op: op, Query: query, Err: err}
+}
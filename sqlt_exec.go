@@ -0,0 +1,121 @@
+package sqlt
+
+import (
+	"context"
+	"time"
+)
+
+// call describe a single query routed by the DB
+type call struct {
+	op    string
+	query string
+	args  []interface{}
+	// write is always routed to master
+	write bool
+	// master route a read to master
+	master bool
+	// dest of Select and Get, used to report the number of rows returned
+	dest interface{}
+	// target is the node explicitly chosen by the caller, bypassing the routing
+	target *node
+	// stream is set when the result is read after fn return, e.g. rows
+	stream bool
+	// opts override the routing of the read, nil unless the call has options
+	opts *callOptions
+}
+
+// run pick the node serving the call and execute fn against it,
+// every query routed by the DB goes through here
+func (db *DB) run(ctx context.Context, c call, fn func(ctx context.Context, n *node, query string) error) error {
+	if !db.enter() {
+		return ErrShutdown
+	}
+	defer db.leave()
+
+	if n := db.singleNode(ctx, c); n != nil {
+		return db.runSingle(ctx, c, n, fn)
+	}
+	ctx = db.decorate(ctx)
+	if db.opts.verbRouting && !c.master && c.target == nil {
+		c.write = !isReadQuery(c.query)
+	}
+	if !c.write && c.target == nil && db.readMode(ctx) == Primary {
+		c.master = true
+	}
+	if !c.write {
+		db.beforeRead(ctx, c)
+	}
+
+	if c.write || c.master {
+		if err := db.waitMaster(ctx); err != nil {
+			return wrapError(err, db.nodeAt(db.master()), c.op, c.query)
+		}
+	}
+
+	n, err := db.pick(ctx, c)
+	if err != nil {
+		return err
+	}
+	n, err = db.acquire(ctx, c, n)
+	if err != nil {
+		return wrapError(err, n, c.op, c.query)
+	}
+	db.report.record(c, n, db.route.Load())
+
+	ctx, cancel := db.queryContext(ctx, n)
+	ctx, unwatch, stop := db.watchdog.watch(ctx, c, n)
+	fnCtx, s := db.stream(ctx, c)
+	// the rows of a streamed call keep the slot and the contexts until they are closed
+	defer s.close(func() {
+		stop()
+		cancel()
+		n.release()
+	})
+
+	start := time.Now()
+	err = n.injected(ctx)
+	if err == nil {
+		err = fn(fnCtx, n, db.comment(ctx, n, db.rebind(n, c.query)))
+	}
+	unwatch()
+	err = n.track(err)
+	elapsed := time.Since(start)
+	db.observe(n, elapsed)
+	db.queryStats.record(c.query, n, elapsed, err)
+	db.logSlowQuery(c, n, elapsed)
+	if err == nil && c.write {
+		db.afterWrite(ctx, c)
+		db.invalidateCache(ctx, c)
+		db.capturePosition(ctx, n)
+	}
+	recordMetadata(ctx, c, n, elapsed, err)
+	db.mirror(ctx, c, elapsed, err)
+	return wrapError(err, n, c.op, c.query)
+}
+
+// pick the node serving the call, reads fail with ErrAllReplicasDown in Secondary mode
+// when no slave is active
+func (db *DB) pick(ctx context.Context, c call) (*node, error) {
+	if c.target != nil {
+		return c.target, nil
+	}
+	if c.write || c.master {
+		return db.nodeAt(db.master()), nil
+	}
+	if c.steered() {
+		if n := db.pickOpt(c.opts); n != nil {
+			return n, nil
+		}
+	}
+	if n := db.pinnedNode(ctx); n != nil {
+		return n, nil
+	}
+	mode := db.readMode(ctx)
+	if mode == Primary || (mode == PrimaryPreferred && !db.route.Load().noMaster) {
+		return db.nodeAt(db.master()), nil
+	}
+	if s := consistencySession(ctx); s != nil {
+		return db.consistentNode(ctx, s), nil
+	}
+	return db.secondary(mode)
+}
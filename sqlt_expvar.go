@@ -0,0 +1,64 @@
+package sqlt
+
+import (
+	"expvar"
+	"fmt"
+	"time"
+)
+
+// expvarNode is the state of a node published by PublishExpvar
+type expvarNode struct {
+	Role      string `json:"role"`
+	Connected bool   `json:"connected"`
+	Disabled  bool   `json:"disabled,omitempty"`
+	Queries   uint64 `json:"queries"`
+	Errors    uint64 `json:"errors"`
+}
+
+// PublishExpvar publish the node connectivity, routed queries of the last minute, QueryStats, leaked rows and last heartbeat
+// as expvar variables under prefix, e.g. "sqlt.orders.nodes". Prefix default to "sqlt." and the group name
+func (db *DB) PublishExpvar(prefix string) error {
+	if prefix == "" {
+		prefix = "sqlt." + db.groupName
+	}
+	vars := map[string]expvar.Func{
+		prefix + ".nodes": func() interface{} {
+			nodes := make(map[string]expvarNode)
+			for _, stat := range db.status() {
+				nodes[stat.Name] = expvarNode{
+					Role:      stat.Role,
+					Connected: stat.Connected,
+					Disabled:  stat.Disabled,
+					Queries:   stat.Queries,
+					Errors:    stat.Errors,
+				}
+			}
+			return nodes
+		},
+		prefix + ".routing": func() interface{} {
+			return db.RoutingReport(time.Minute)
+		},
+		prefix + ".queries": func() interface{} {
+			return db.QueryStats()
+		},
+		prefix + ".leaks": func() interface{} {
+			return map[string]interface{}{"open": db.LeakedRows(), "total": db.LeakedRowsTotal()}
+		},
+		prefix + ".heartbeat": func() interface{} {
+			db.mu.Lock()
+			defer db.mu.Unlock()
+			return map[string]interface{}{"running": db.heartbeatRunning(), "last_beat": db.lastBeat}
+		},
+	}
+
+	// expvar panic on duplicate names
+	for name := range vars {
+		if expvar.Get(name) != nil {
+			return fmt.Errorf("expvar %q is already published", name)
+		}
+	}
+	for name, v := range vars {
+		expvar.Publish(name, v)
+	}
+	return nil
+}
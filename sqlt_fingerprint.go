@@ -0,0 +1,194 @@
+package sqlt
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxFingerprints bound the number of statements aggregated per DB, new statements are
+// not tracked once the limit is reached
+const maxFingerprints = 1000
+
+// WithQueryStats aggregate the count and latency of every statement per node, see QueryStats
+func WithQueryStats() Option {
+	return func(o *options) {
+		o.queryStats = true
+		o.pipeline = true
+	}
+}
+
+// QueryStat is the aggregated count and latency of a statement on a node
+type QueryStat struct {
+	Fingerprint  string        `json:"fingerprint"`
+	Node         string        `json:"node"`
+	Count        uint64        `json:"count"`
+	Errors       uint64        `json:"errors"`
+	TotalLatency time.Duration `json:"total_latency_ns"`
+	MaxLatency   time.Duration `json:"max_latency_ns"`
+}
+
+// AvgLatency return the average latency of the statement
+func (s QueryStat) AvgLatency() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Count)
+}
+
+type fingerprintKey struct {
+	fingerprint string
+	node        string
+}
+
+// queryStats aggregate the statements by fingerprint and node
+type queryStats struct {
+	mu    sync.Mutex
+	stats map[fingerprintKey]*QueryStat
+}
+
+func newQueryStats(enabled bool) *queryStats {
+	if !enabled {
+		return nil
+	}
+	return &queryStats{stats: make(map[fingerprintKey]*QueryStat)}
+}
+
+func (qs *queryStats) record(query string, n *node, d time.Duration, err error) {
+	if qs == nil {
+		return
+	}
+	key := fingerprintKey{fingerprint: Fingerprint(query), node: n.name}
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	s, ok := qs.stats[key]
+	if !ok {
+		if len(qs.stats) >= maxFingerprints {
+			return
+		}
+		s = &QueryStat{Fingerprint: key.fingerprint, Node: key.node}
+		qs.stats[key] = s
+	}
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+	s.TotalLatency += d
+	if d > s.MaxLatency {
+		s.MaxLatency = d
+	}
+}
+
+// QueryStats return the aggregated statements of every node, the most executed first.
+// Nil when not enabled by WithQueryStats
+func (db *DB) QueryStats() []QueryStat {
+	qs := db.queryStats
+	if qs == nil {
+		return nil
+	}
+
+	qs.mu.Lock()
+	stats := make([]QueryStat, 0, len(qs.stats))
+	for _, s := range qs.stats {
+		stats = append(stats, *s)
+	}
+	qs.mu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		if stats[i].Fingerprint != stats[j].Fingerprint {
+			return stats[i].Fingerprint < stats[j].Fingerprint
+		}
+		return stats[i].Node < stats[j].Node
+	})
+	return stats
+}
+
+// Fingerprint normalize the query by replacing literals and bindvars with ?, collapsing
+// lists of values and whitespace, e.g. "SELECT * FROM t WHERE id IN (1, 2) AND name = 'a'"
+// become "SELECT * FROM t WHERE id IN (?) AND name = ?"
+func Fingerprint(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	space := false
+	for i := 0; i < len(query); {
+		ch := query[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			space = b.Len() > 0
+			i++
+			continue
+		case ch == '\'':
+			i = skipQuoted(query, i)
+			ch = '?'
+		case ch >= '0' && ch <= '9' && !identChar(last(&b)):
+			for i < len(query) && (isDigit(query[i]) || query[i] == '.') {
+				i++
+			}
+			ch = '?'
+		case (ch == '$' || ch == ':' || ch == '@') && i+1 < len(query) && identChar(query[i+1]) && last(&b) != ch:
+			i++
+			for i < len(query) && identChar(query[i]) {
+				i++
+			}
+			ch = '?'
+		default:
+			i++
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteByte(ch)
+	}
+	return collapseLists(b.String())
+}
+
+// skipQuoted return the index after the quoted string starting at i, a doubled quote is escaped
+func skipQuoted(s string, i int) int {
+	quote := s[i]
+	for i++; i < len(s); i++ {
+		if s[i] == quote {
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return i
+}
+
+// collapseLists replace lists of bindvars such as (?, ?, ?) with (?), and rows of values
+// such as (?), (?) with (?)
+func collapseLists(s string) string {
+	for {
+		next := listReplacer.Replace(s)
+		if next == s {
+			return s
+		}
+		s = next
+	}
+}
+
+var listReplacer = strings.NewReplacer("?, ?", "?", "?,?", "?", "(?), (?)", "(?)", "(?),(?)", "(?)")
+
+func last(b *strings.Builder) byte {
+	s := b.String()
+	if len(s) == 0 {
+		return 0
+	}
+	return s[len(s)-1]
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func identChar(ch byte) bool {
+	return ch == '_' || isDigit(ch) || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
@@ -0,0 +1,100 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Get a single row into T using slave
+func Get[T any](ctx context.Context, db *DB, query string, args ...interface{}) (T, error) {
+	var dest T
+	err := db.GetContext(ctx, &dest, query, args...)
+	return dest, err
+}
+
+// GetMaster get a single row into T using master
+func GetMaster[T any](ctx context.Context, db *DB, query string, args ...interface{}) (T, error) {
+	var dest T
+	err := db.GetMasterContext(ctx, &dest, query, args...)
+	return dest, err
+}
+
+// Select rows into []T using slave
+func Select[T any](ctx context.Context, db *DB, query string, args ...interface{}) ([]T, error) {
+	var dest []T
+	err := db.SelectContext(ctx, &dest, query, args...)
+	return dest, err
+}
+
+// SelectMaster select rows into []T using master
+func SelectMaster[T any](ctx context.Context, db *DB, query string, args ...interface{}) ([]T, error) {
+	var dest []T
+	err := db.SelectMasterContext(ctx, &dest, query, args...)
+	return dest, err
+}
+
+// Rows stream the rows of the query from slave into T without loading them all in memory,
+// iteration stop at the first error
+func Rows[T any](ctx context.Context, db *DB, query string, args ...interface{}) iter.Seq2[T, error] {
+	return rowsOf[T](ctx, db, call{op: "Rows", query: query, args: args, stream: true})
+}
+
+// RowsMaster stream the rows of the query from master into T
+func RowsMaster[T any](ctx context.Context, db *DB, query string, args ...interface{}) iter.Seq2[T, error] {
+	return rowsOf[T](ctx, db, call{op: "RowsMaster", query: query, args: args, master: true, stream: true})
+}
+
+func rowsOf[T any](ctx context.Context, db *DB, c call) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		var rows *sqlx.Rows
+		err := db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+			var err error
+			rows, err = db.conn(ctx, n).QueryxContext(ctx, query, c.args...)
+			return err
+		})
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+
+		scan := rows.StructScan
+		if scannable(reflect.TypeFor[T]()) {
+			scan = func(dest interface{}) error { return rows.Scan(dest) }
+		}
+		for rows.Next() {
+			var dest T
+			if err := scan(&dest); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(dest, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+var scannerType = reflect.TypeFor[sql.Scanner]()
+
+// scannable report whether t is scanned as a single column, like sqlx does for
+// non struct types, scanners and structs without exported fields
+func scannable(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(scannerType) || t.Kind() != reflect.Struct {
+		return true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return false
+		}
+	}
+	return true
+}
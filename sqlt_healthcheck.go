@@ -0,0 +1,37 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// HealthCheck is a query used to check node health instead of ping, e.g. a replica in crash
+// recovery still accept connection but fail
+//
+//	HealthCheck{Query: "SELECT pg_is_in_recovery()", Expected: "true", MasterExpected: "false"}
+type HealthCheck struct {
+	// Query must return a single row with a single column
+	Query string
+	// Expected is compared with the returned value, any value is accepted when empty
+	Expected string
+	// MasterExpected is compared with the value returned by master, Expected is used when empty
+	MasterExpected string
+}
+
+func (hc HealthCheck) run(ctx context.Context, db *sqlx.DB, master bool) error {
+	var result string
+	if err := db.QueryRowContext(ctx, hc.Query).Scan(&result); err != nil {
+		return err
+	}
+
+	expected := hc.Expected
+	if master && hc.MasterExpected != "" {
+		expected = hc.MasterExpected
+	}
+	if expected != "" && result != expected {
+		return fmt.Errorf("health check returned %q, expected %q", result, expected)
+	}
+	return nil
+}
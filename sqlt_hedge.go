@@ -0,0 +1,112 @@
+package sqlt
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// WithHedging send a read to a second slave when the first slave hasn't responded within delay,
+// the first successful result is returned and the other read is canceled. Only Select and Get
+// are hedged, use WithoutHedging to disable it for a single call
+func WithHedging(delay time.Duration) Option {
+	return func(o *options) {
+		o.hedgeDelay = delay
+	}
+}
+
+// readFunc scan the query result of the node into dest
+type readFunc func(ctx context.Context, n *node, query string, dest interface{}) error
+
+// runRead run the read, hedged to a second slave when enabled
+func (db *DB) runRead(ctx context.Context, c call, read readFunc) error {
+	return db.cached(ctx, c, func(ctx context.Context) error {
+		if first, second := db.hedgeNodes(ctx, c); second != nil {
+			return db.hedge(ctx, c, first, second, read)
+		}
+		return db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+			return read(ctx, n, query, c.dest)
+		})
+	})
+}
+
+// hedgeNodes return the slave serving the read and the slave receiving the hedged read,
+// nil when the read is not hedged
+func (db *DB) hedgeNodes(ctx context.Context, c call) (*node, *node) {
+	if db.opts.hedgeDelay <= 0 || c.write || c.master || c.target != nil || hedgingDisabled(ctx) || consistencySession(ctx) != nil || pinned(ctx) || c.steered() {
+		return nil, nil
+	}
+	if c.dest == nil || reflect.TypeOf(c.dest).Kind() != reflect.Ptr || (db.opts.verbRouting && !isReadQuery(c.query)) {
+		return nil, nil
+	}
+	r := db.route.Load()
+	if len(r.slaves) < 2 {
+		return nil, nil
+	}
+	first, err := db.pick(ctx, c)
+	if err != nil || first.index == r.master {
+		return nil, nil
+	}
+	offset := db.random.IntN(len(r.slaves))
+	for i := range r.slaves {
+		if n := r.slaves[(offset+i)%len(r.slaves)]; n != first {
+			return first, n
+		}
+	}
+	return nil, nil
+}
+
+type hedgeResult struct {
+	n       *node
+	dest    interface{}
+	err     error
+	elapsed time.Duration
+}
+
+// hedge read from first, and from second as well when first is slower than the hedge delay.
+// Every attempt is a call targeting its node, so it is limited, watched and counted on the node
+// serving it, and scan into its own dest. The winner is copied into the call dest
+func (db *DB) hedge(ctx context.Context, c call, first, second *node, read readFunc) error {
+	// attempts run concurrently, only the winner fill the result metadata
+	meta := resultMetadata(ctx)
+	attemptCtx, cancel := context.WithCancel(context.WithValue(ctx, metadataKey{}, (*ResultMetadata)(nil)))
+	defer cancel()
+
+	results := make(chan hedgeResult, 2)
+	attempt := func(n *node) {
+		a := c
+		a.target = n
+		a.dest = reflect.New(reflect.TypeOf(c.dest).Elem()).Interface()
+		start := time.Now()
+		err := db.run(attemptCtx, a, func(ctx context.Context, n *node, query string) error {
+			return read(ctx, n, query, a.dest)
+		})
+		results <- hedgeResult{n: n, dest: a.dest, err: err, elapsed: time.Since(start)}
+	}
+
+	go attempt(first)
+	timer := time.NewTimer(db.opts.hedgeDelay)
+	defer timer.Stop()
+	pending := 1
+
+	for {
+		select {
+		case <-timer.C:
+			pending++
+			db.report.hedge()
+			go attempt(second)
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				reflect.ValueOf(c.dest).Elem().Set(reflect.ValueOf(r.dest).Elem())
+				if meta != nil {
+					recordMetadata(context.WithValue(ctx, metadataKey{}, meta), c, r.n, r.elapsed, nil)
+				}
+				return nil
+			}
+			if pending == 0 {
+				return r.err
+			}
+		}
+	}
+}
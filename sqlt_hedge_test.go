@@ -0,0 +1,99 @@
+package sqlt
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHedging(t *testing.T) {
+	const slow = 500 * time.Millisecond
+	tests := []struct {
+		name string
+		ctx  func() context.Context
+		read func(ctx context.Context, db *DB) (string, error)
+		// hedged is set when every read is answered by the fast slave
+		hedged bool
+	}{
+		{
+			name: "get hedged",
+			ctx:  context.Background,
+			read: func(ctx context.Context, db *DB) (string, error) {
+				var dsn string
+				err := db.GetContext(ctx, &dsn, "SELECT dsn")
+				return dsn, err
+			},
+			hedged: true,
+		},
+		{
+			name: "select hedged",
+			ctx:  context.Background,
+			read: func(ctx context.Context, db *DB) (string, error) {
+				var dsns []string
+				err := db.SelectContext(ctx, &dsns, "SELECT dsn")
+				if len(dsns) != 1 {
+					return "", err
+				}
+				return dsns[0], err
+			},
+			hedged: true,
+		},
+		{
+			name: "hedging disabled by the context",
+			ctx:  func() context.Context { return WithoutHedging(context.Background()) },
+			read: func(ctx context.Context, db *DB) (string, error) {
+				var dsn string
+				err := db.GetContext(ctx, &dsn, "SELECT dsn")
+				return dsn, err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDB(t, 2, WithHedging(10*time.Millisecond))
+			if err := db.DelayNode("slave-1", slow); err != nil {
+				t.Fatal(err)
+			}
+
+			// round robin send one of the two reads to the slow slave first
+			slowReads := 0
+			for i := 0; i < 2; i++ {
+				ctx, meta := WithResultMetadata(tt.ctx())
+				start := time.Now()
+				dsn, err := tt.read(ctx, db)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if meta.Node != dsn {
+					t.Fatalf("metadata of %q, read served by %q", meta.Node, dsn)
+				}
+				if dsn == "slave-1" {
+					slowReads++
+					continue
+				}
+				if elapsed := time.Since(start); elapsed >= slow {
+					t.Fatalf("read of the fast slave took %v", elapsed)
+				}
+			}
+			if hedged := slowReads == 0; hedged != tt.hedged {
+				t.Fatalf("hedged %v, want %v", hedged, tt.hedged)
+			}
+
+			// the canceled read of the slow slave isn't a node error
+			n, err := db.node("slave-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			deadline := time.Now().Add(time.Second)
+			for n.queries.Load() == 0 {
+				if time.Now().After(deadline) {
+					t.Fatal("read of the slow slave not canceled")
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if errs := n.errors.Load(); errs != 0 {
+				t.Fatalf("%d errors on the slow slave", errs)
+			}
+		})
+	}
+}
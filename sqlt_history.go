@@ -0,0 +1,90 @@
+package sqlt
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultHistorySize = 100
+
+// node events recorded in the history
+const (
+	EventUp       = "up"
+	EventDown     = "down"
+	EventMaster   = "master"
+	EventDisabled = "disabled"
+	EventEnabled  = "enabled"
+	EventAdded    = "added"
+	EventRemoved  = "removed"
+)
+
+// NodeEvent is a transition of the node, e.g. the node went down
+type NodeEvent struct {
+	Node  string    `json:"node"`
+	Event string    `json:"event"`
+	Error string    `json:"error,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// WithHistorySize set the number of node events kept by the DB, default to 100.
+// Zero or negative disable the history
+func WithHistorySize(size int) Option {
+	return func(o *options) {
+		o.historySize = size
+	}
+}
+
+// history is a ring buffer of the last node events
+type history struct {
+	mu     sync.Mutex
+	events []NodeEvent
+	next   int
+	full   bool
+}
+
+func newHistory(size int) *history {
+	if size <= 0 {
+		return nil
+	}
+	return &history{events: make([]NodeEvent, size)}
+}
+
+func (h *history) add(e NodeEvent) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events[h.next] = e
+	h.next = (h.next + 1) % len(h.events)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list return the events, oldest first
+func (h *history) list() []NodeEvent {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.full {
+		return append([]NodeEvent(nil), h.events[:h.next]...)
+	}
+	return append(append([]NodeEvent(nil), h.events[h.next:]...), h.events[:h.next]...)
+}
+
+// recordEvent add the node event to the history
+func (db *DB) recordEvent(n *node, event string, err error) {
+	e := NodeEvent{Node: n.name, Event: event, Time: time.Now()}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	db.history.add(e)
+}
+
+// History return the last node events, oldest first
+func (db *DB) History() []NodeEvent {
+	return db.history.list()
+}
@@ -0,0 +1,87 @@
+package sqlt
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// CacheHooks is notified around queries so cache-aside layers can be kept in sync centrally
+type CacheHooks interface {
+	// BeforeRead is called before a read with the key set by WithCacheKey
+	BeforeRead(ctx context.Context, key string)
+	// AfterWrite is called after a successful write with the keys set by WithInvalidateKeys,
+	// or the tables written by the query when no key is set
+	AfterWrite(ctx context.Context, keys []string)
+}
+
+// WithCacheHooks set the hooks notified on every read and write
+func WithCacheHooks(hooks CacheHooks) Option {
+	return func(o *options) {
+		o.cacheHooks = hooks
+		o.pipeline = true
+	}
+}
+
+type cacheKey int
+
+const (
+	readKey cacheKey = iota
+	invalidateKey
+)
+
+// WithCacheKey return a context which pass the key to CacheHooks.BeforeRead
+func WithCacheKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, readKey, key)
+}
+
+// WithInvalidateKeys return a context which pass the keys to CacheHooks.AfterWrite
+// instead of the tables written by the query
+func WithInvalidateKeys(ctx context.Context, keys ...string) context.Context {
+	return context.WithValue(ctx, invalidateKey, keys)
+}
+
+func (db *DB) beforeRead(ctx context.Context, c call) {
+	if db.opts.cacheHooks == nil {
+		return
+	}
+	if key, ok := ctx.Value(readKey).(string); ok {
+		db.opts.cacheHooks.BeforeRead(ctx, key)
+	}
+}
+
+func (db *DB) afterWrite(ctx context.Context, c call) {
+	if db.opts.cacheHooks == nil {
+		return
+	}
+
+	if keys := writeKeys(ctx, c); len(keys) > 0 {
+		db.opts.cacheHooks.AfterWrite(ctx, keys)
+	}
+}
+
+// writeKeys return the keys set by WithInvalidateKeys, or the tables written by the query
+func writeKeys(ctx context.Context, c call) []string {
+	if keys, ok := ctx.Value(invalidateKey).([]string); ok {
+		return keys
+	}
+	return writtenTables(c.query)
+}
+
+var (
+	writtenTableRegexp = regexp.MustCompile("(?i)\\b(?:insert\\s+(?:ignore\\s+)?into|replace\\s+into|update|delete\\s+from)\\s+([\\w.\"`]+)")
+	identQuoteReplacer = strings.NewReplacer("\"", "", "`", "")
+)
+
+// writtenTables return the tables written by the query
+func writtenTables(query string) []string {
+	var tables []string
+	for _, match := range writtenTableRegexp.FindAllStringSubmatch(query, -1) {
+		// skip upsert clause such as "ON CONFLICT DO UPDATE SET"
+		if strings.EqualFold(match[1], "set") {
+			continue
+		}
+		tables = append(tables, identQuoteReplacer.Replace(match[1]))
+	}
+	return tables
+}
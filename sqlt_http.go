@@ -0,0 +1,91 @@
+package sqlt
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// StatusHandler return http handler which respond with the full database status in JSON
+func (db *DB) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(db.statusResponse())
+	})
+}
+
+// HealthzHandler return http handler which respond 200 only when the master and at least
+// minSlaves slaves are reachable, otherwise 503. Suitable for readiness probes
+func (db *DB) HealthzHandler(minSlaves int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := db.checkHealth(minSlaves); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+}
+
+func (db *DB) statusResponse() statusResponse {
+	stats, _ := db.GetStatus()
+
+	db.mu.Lock()
+	lastBeat := db.lastBeat
+	db.mu.Unlock()
+
+	var dbs interface{} = stats
+	if len(db.opts.statusFields) > 0 {
+		dbs = renameFields(stats, db.opts.statusFields)
+	}
+
+	return statusResponse{
+		Dbs:       dbs,
+		Heartbeat: db.heartbeatRunning(),
+		Lastbeat:  lastBeat,
+		Pool:      db.Stats(),
+		History:   db.History(),
+	}
+}
+
+// renameFields convert the status into JSON objects with renamed fields
+func renameFields(stats []DbStatus, names map[string]string) []map[string]interface{} {
+	b, _ := json.Marshal(stats)
+	var objects []map[string]interface{}
+	json.Unmarshal(b, &objects)
+
+	for _, obj := range objects {
+		for from, to := range names {
+			if val, ok := obj[from]; ok {
+				delete(obj, from)
+				obj[to] = val
+			}
+		}
+	}
+	return objects
+}
+
+// checkHealth return error if the master or not enough slaves are connected
+func (db *DB) checkHealth(minSlaves int) error {
+	stats, err := db.GetStatus()
+	if err != nil {
+		return err
+	}
+
+	connected := 0
+	for _, stat := range stats {
+		if stat.Role == "master" && !stat.Connected {
+			return &Error{Node: stat.Name, Op: "Ping", Err: ErrMasterUnavailable}
+		}
+		if stat.Role != "master" && stat.Connected {
+			connected++
+		}
+	}
+	if connected == 0 && minSlaves > 0 {
+		return ErrAllReplicasDown
+	}
+	if connected < minSlaves {
+		return fmt.Errorf("%d of %d required slaves connected", connected, minSlaves)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrDuplicateExec returned by ExecIdempotent when the key is already executed
+var ErrDuplicateExec = errors.New("Duplicate idempotency key")
+
+// IdempotencyStore record the keys of executed writes
+type IdempotencyStore interface {
+	// Claim record the key inside the write transaction, return false when the key is already recorded
+	Claim(ctx context.Context, tx *sqlx.Tx, key string) (bool, error)
+	// Release is called when the write failed after the key is claimed
+	Release(ctx context.Context, key string) error
+}
+
+// TableStore record idempotency keys in a table on master, in the same transaction as the write.
+// The table must have a unique key column, e.g.
+//
+//	CREATE TABLE sqlt_idempotency (key VARCHAR(255) PRIMARY KEY, created_at TIMESTAMP NOT NULL)
+type TableStore struct {
+	Table string
+}
+
+// Claim insert the key, duplicate key is detected by zero affected rows
+func (ts TableStore) Claim(ctx context.Context, tx *sqlx.Tx, key string) (bool, error) {
+	query := "INSERT INTO " + ts.Table + " (key, created_at) VALUES (?, ?) ON CONFLICT DO NOTHING"
+	if detectDialect(tx.DriverName(), nil) == DialectMySQL {
+		query = "INSERT IGNORE INTO " + ts.Table + " (`key`, created_at) VALUES (?, ?)"
+	}
+
+	result, err := tx.ExecContext(ctx, tx.Rebind(query), key, time.Now())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// Release do nothing, the claim is rolled back together with the failed write
+func (ts TableStore) Release(ctx context.Context, key string) error {
+	return nil
+}
+
+// WithIdempotencyStore set the store used by ExecIdempotent, default to TableStore on "sqlt_idempotency" table
+func WithIdempotencyStore(store IdempotencyStore) Option {
+	return func(o *options) {
+		o.idempotencyStore = store
+	}
+}
+
+// ExecIdempotent execute the query on master only once for the given key, ErrDuplicateExec is
+// returned without executing the query when the key is already executed
+func (db *DB) ExecIdempotent(ctx context.Context, key string, query string, args ...interface{}) (sql.Result, error) {
+	store := db.opts.idempotencyStore
+	var result sql.Result
+
+	err := db.InTx(ctx, nil, func(tx *sqlx.Tx) error {
+		claimed, err := store.Claim(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return ErrDuplicateExec
+		}
+
+		result, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			store.Release(ctx, key)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// In expand slice arguments of the query with sqlx.In and rebind it for the master driver
+func (db *DB) In(query string, args ...interface{}) (string, []interface{}, error) {
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return "", nil, err
+	}
+	return db.RebindMaster(query), args, nil
+}
+
+// SelectIn expand slice arguments and select using slave db
+func (db *DB) SelectIn(dest interface{}, query string, args ...interface{}) error {
+	return db.SelectInContext(context.Background(), dest, query, args...)
+}
+
+// SelectInContext expand slice arguments and select using slave db
+func (db *DB) SelectInContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return err
+	}
+	return db.runRead(ctx, call{op: "SelectIn", query: query, args: args, dest: dest}, func(ctx context.Context, n *node, query string, dest interface{}) error {
+		return n.db().SelectContext(ctx, dest, n.rebind(query), args...)
+	})
+}
+
+// GetIn expand slice arguments and get using slave db
+func (db *DB) GetIn(dest interface{}, query string, args ...interface{}) error {
+	return db.GetInContext(context.Background(), dest, query, args...)
+}
+
+// GetInContext expand slice arguments and get using slave db
+func (db *DB) GetInContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return err
+	}
+	return db.runRead(ctx, call{op: "GetIn", query: query, args: args, dest: dest}, func(ctx context.Context, n *node, query string, dest interface{}) error {
+		return n.db().GetContext(ctx, dest, n.rebind(query), args...)
+	})
+}
+
+// ExecIn expand slice arguments and exec using master db
+func (db *DB) ExecIn(query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecInContext(context.Background(), query, args...)
+}
+
+// ExecInContext expand slice arguments and exec using master db
+func (db *DB) ExecInContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var result sql.Result
+	err = db.run(ctx, call{op: "ExecIn", query: query, args: args, write: true}, func(ctx context.Context, n *node, query string) error {
+		var err error
+		result, err = n.db().ExecContext(ctx, n.rebind(query), args...)
+		return err
+	})
+	recordAffected(ctx, result)
+	return result, err
+}
@@ -0,0 +1,69 @@
+package sqlt
+
+import "context"
+
+// WithMaxInflight limit the number of concurrent queries of the named node, empty name limit every node.
+// A slave read over the limit overflow to another slave with free capacity, other queries
+// wait until the node has capacity or the context is done. Streamed rows hold their slot until closed
+func WithMaxInflight(name string, n int) Option {
+	return func(o *options) {
+		o.maxInflight[name] = n
+		o.pipeline = true
+	}
+}
+
+// applyLimit set the concurrency limit of the node
+func (db *DB) applyLimit(n *node) {
+	max, ok := db.opts.maxInflight[n.name]
+	if !ok {
+		max = db.opts.maxInflight[""]
+	}
+	if max > 0 {
+		n.limit = make(chan struct{}, max)
+	}
+}
+
+// tryAcquire take a slot of the node without waiting
+func (n *node) tryAcquire() bool {
+	if n.limit == nil {
+		n.inflight.Add(1)
+		return true
+	}
+	select {
+	case n.limit <- struct{}{}:
+		n.inflight.Add(1)
+		return true
+	default:
+		return false
+	}
+}
+
+func (n *node) release() {
+	n.inflight.Add(-1)
+	if n.limit != nil {
+		<-n.limit
+	}
+}
+
+// acquire a slot on the node serving the call, return the node holding the slot
+// which is another slave when the read overflowed
+func (db *DB) acquire(ctx context.Context, c call, n *node) (*node, error) {
+	if n.tryAcquire() {
+		return n, nil
+	}
+	if !c.write && !c.master && c.target == nil && !pinned(ctx) && consistencySession(ctx) == nil && !c.steered() {
+		for _, slave := range db.route.Load().slaves {
+			if slave != n && slave.tryAcquire() {
+				return slave, nil
+			}
+		}
+	}
+
+	select {
+	case n.limit <- struct{}{}:
+		n.inflight.Add(1)
+		return n, nil
+	case <-ctx.Done():
+		return n, ctx.Err()
+	}
+}
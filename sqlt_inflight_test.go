@@ -0,0 +1,64 @@
+package sqlt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMaxInflight(t *testing.T) {
+	tests := []struct {
+		name string
+		// hold run a query keeping the slot of master until release is called
+		hold func(db *DB) (release func(), err error)
+	}{
+		{
+			name: "streamed rows",
+			hold: func(db *DB) (func(), error) {
+				rows, err := db.QueryxMaster("SELECT dsn")
+				if err != nil {
+					return nil, err
+				}
+				return func() { rows.Close() }, nil
+			},
+		},
+		{
+			name: "streamed rows of a prepared statement",
+			hold: func(db *DB) (func(), error) {
+				stmt, err := db.PreparexMaster("SELECT dsn")
+				if err != nil {
+					return nil, err
+				}
+				rows, err := stmt.QueryMaster()
+				if err != nil {
+					return nil, err
+				}
+				return func() { rows.Close(); stmt.Close() }, nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDB(t, 1, WithMaxInflight("master", 1))
+			release, err := tt.hold(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the write wait for the slot held by the open rows
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			if _, err := db.ExecContext(ctx, "UPDATE stock SET quantity = 0"); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("write with the rows open: %v, want deadline exceeded", err)
+			}
+
+			release()
+			ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if _, err := db.ExecContext(ctx, "UPDATE stock SET quantity = 0"); err != nil {
+				t.Fatalf("write after the rows are closed: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// LagProbe measure the replication lag of a slave, the probe run with the heartbeat
+// on every slave which answered the ping
+type LagProbe interface {
+	Lag(ctx context.Context, conn *sql.DB) (time.Duration, error)
+}
+
+// LagProbeFunc adapt a function into a LagProbe
+type LagProbeFunc func(ctx context.Context, conn *sql.DB) (time.Duration, error)
+
+// Lag call f(ctx, conn)
+func (f LagProbeFunc) Lag(ctx context.Context, conn *sql.DB) (time.Duration, error) {
+	return f(ctx, conn)
+}
+
+// WithLagProbe measure the lag of every slave with probe, the lag is reported in the status
+// and used by MaxStaleness. The lag of a slave is unknown until the first probe succeeded
+func WithLagProbe(probe LagProbe) Option {
+	return func(o *options) {
+		o.lagProbe = probe
+	}
+}
+
+// measureLag return the lag of the node, -1 when the probe failed
+func (db *DB) measureLag(ctx context.Context, n *node) time.Duration {
+	lag, err := db.opts.lagProbe.Lag(ctx, n.db().DB)
+	if err != nil {
+		db.opts.logger.Printf("sqlt: lag probe of node %s failed: %v", n.name, err)
+		return -1
+	}
+	if lag < 0 {
+		lag = 0
+	}
+	return lag
+}
+
+// within report whether the node lag is known and at most maxLag, master is never stale
+func (db *DB) within(n *node, maxLag time.Duration) bool {
+	if n.index == db.route.Load().master {
+		return true
+	}
+	lag := n.lag.Load()
+	return lag >= 0 && time.Duration(lag) <= maxLag
+}
@@ -0,0 +1,43 @@
+package sqlt
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// latencySamples is the number of recent query latencies kept per node
+const latencySamples = 256
+
+// latencyWindow keep the latency of the recent queries of a node
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples [latencySamples]time.Duration
+	next    int
+	count   int
+}
+
+func (w *latencyWindow) record(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % latencySamples
+	w.count = min(w.count+1, latencySamples)
+}
+
+// summary return the average and 95th percentile of the recent latencies
+func (w *latencyWindow) summary() (avg, p95 time.Duration) {
+	w.mu.Lock()
+	samples := slices.Clone(w.samples[:w.count])
+	w.mu.Unlock()
+	if len(samples) == 0 {
+		return 0, 0
+	}
+
+	var total time.Duration
+	for _, d := range samples {
+		total += d
+	}
+	slices.Sort(samples)
+	return total / time.Duration(len(samples)), samples[(len(samples)*95-1)/100]
+}
@@ -0,0 +1,124 @@
+package sqlt
+
+import (
+	"database/sql"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LeakDetector report the rows handed out by sqlt which are still open after Threshold,
+// every leaked rows hold a connection of its node until closed
+type LeakDetector struct {
+	Threshold time.Duration
+	// OnLeak is called once for every leaked rows, while it is still open
+	OnLeak func(r LeakedRows)
+}
+
+// LeakedRows is rows which was not closed within the leak detector threshold
+type LeakedRows struct {
+	Op    string `json:"op"`
+	Query string `json:"query"`
+	Node  string `json:"node"`
+	// Caller is the first function outside of sqlt opening the rows, as "file:line"
+	Caller string        `json:"caller"`
+	Opened time.Time     `json:"opened"`
+	Age    time.Duration `json:"age"`
+}
+
+// WithLeakDetector log and report the rows not closed within the detector threshold,
+// see LeakedRows
+func WithLeakDetector(d LeakDetector) Option {
+	return func(o *options) {
+		if d.Threshold > 0 {
+			o.leakDetector = &d
+		}
+	}
+}
+
+// leakDetector hold the leaked rows until they are closed
+type leakDetector struct {
+	LeakDetector
+	logger Logger
+	total  atomic.Uint64
+
+	mu     sync.Mutex
+	leaked map[*sql.Rows]*LeakedRows
+}
+
+func newLeakDetector(d *LeakDetector, logger Logger) *leakDetector {
+	if d == nil {
+		return nil
+	}
+	return &leakDetector{LeakDetector: *d, logger: logger, leaked: make(map[*sql.Rows]*LeakedRows)}
+}
+
+// track report the rows when it is still open after the threshold
+func (d *leakDetector) track(c call, n *node, rows *sql.Rows) {
+	if d == nil || rows == nil {
+		return
+	}
+
+	var pcs [32]uintptr
+	depth := runtime.Callers(2, pcs[:])
+	opened := time.Now()
+	time.AfterFunc(d.Threshold, func() {
+		if closed(rows) {
+			return
+		}
+		leak := &LeakedRows{Op: c.op, Query: c.query, Node: n.name, Caller: caller(pcs[:depth]), Opened: opened}
+		d.mu.Lock()
+		d.leaked[rows] = leak
+		d.mu.Unlock()
+		d.total.Add(1)
+
+		report := *leak
+		report.Age = time.Since(opened)
+		d.logger.Printf("sqlt: rows of %s on node %s not closed after %s, opened at %s: %s",
+			report.Op, report.Node, report.Age.Round(time.Millisecond), report.Caller, report.Query)
+		if d.OnLeak != nil {
+			d.OnLeak(report)
+		}
+	})
+}
+
+// closed report whether the rows is closed, Columns only fail once the rows is closed
+func closed(rows *sql.Rows) bool {
+	_, err := rows.Columns()
+	return err != nil
+}
+
+// LeakedRows return the leaked rows still open, oldest first. Nil when the leak detector is not enabled
+func (db *DB) LeakedRows() []LeakedRows {
+	d := db.leaks
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	leaks := make([]LeakedRows, 0, len(d.leaked))
+	for rows, leak := range d.leaked {
+		if closed(rows) {
+			delete(d.leaked, rows)
+			continue
+		}
+		open := *leak
+		open.Age = time.Since(leak.Opened)
+		leaks = append(leaks, open)
+	}
+	sort.Slice(leaks, func(i, j int) bool {
+		return leaks[i].Opened.Before(leaks[j].Opened)
+	})
+	return leaks
+}
+
+// LeakedRowsTotal return the number of rows reported by the leak detector since the DB was opened
+func (db *DB) LeakedRowsTotal() uint64 {
+	if db.leaks == nil {
+		return 0
+	}
+	return db.leaks.total.Load()
+}
@@ -0,0 +1,51 @@
+package sqlt
+
+import (
+	"fmt"
+	"time"
+)
+
+// Logger is used to log health changes, failover and slow queries, *log.Logger satisfy this interface
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...interface{}) {}
+
+// WithLogger set the logger, nothing is logged by default
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		if l != nil {
+			o.logger = l
+		}
+	}
+}
+
+// WithSlowQueryThreshold log every query taking longer than d with its node, query, args and duration
+func WithSlowQueryThreshold(d time.Duration) Option {
+	return func(o *options) {
+		o.slowQuery = d
+		o.pipeline = true
+	}
+}
+
+// WithRedactedArgs hide the query args in the slow query log
+func WithRedactedArgs() Option {
+	return func(o *options) {
+		o.redactArgs = true
+	}
+}
+
+// logSlowQuery log the call when it exceed the slow query threshold
+func (db *DB) logSlowQuery(c call, n *node, d time.Duration) {
+	if db.opts.slowQuery <= 0 || d < db.opts.slowQuery {
+		return
+	}
+	args := fmt.Sprint(c.args)
+	if db.opts.redactArgs {
+		args = fmt.Sprintf("[%d redacted]", len(c.args))
+	}
+	db.opts.logger.Printf("sqlt: slow %s on %s took %s: %s args=%s", c.op, n.name, d, c.query, args)
+}
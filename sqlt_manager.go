@@ -0,0 +1,273 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// GroupConfig describe a single connection group opened by the manager
+type GroupConfig struct {
+	Name    string
+	Driver  string
+	Sources string
+	Options []Option
+}
+
+// Manager hold multiple connection groups, e.g. one group per logical database,
+// and share a single heartbeat goroutine across them
+type Manager struct {
+	mu     sync.RWMutex
+	groups map[string]*DB
+
+	// beatMu guard beating, the shared heartbeat which is nil when not running
+	beatMu  sync.Mutex
+	beating *heartbeat
+}
+
+// NewManager return an empty manager, use Add to register the groups
+func NewManager() *Manager {
+	return &Manager{groups: make(map[string]*DB)}
+}
+
+// OpenManager open every group. Like Open, the manager is returned together with the ping error
+// of the groups, the error is only fatal when the manager is nil
+func OpenManager(ctx context.Context, groups ...GroupConfig) (*Manager, error) {
+	m := NewManager()
+	var pingErrs []error
+	for _, g := range groups {
+		db, err := openContextConnection(ctx, g.Driver, g.Sources, g.Name, g.Options)
+		if db == nil {
+			m.Close()
+			return nil, fmt.Errorf("open group %s: %w", g.Name, err)
+		}
+		if err != nil {
+			pingErrs = append(pingErrs, err)
+		}
+		if err := m.Add(g.Name, db); err != nil {
+			db.Close()
+			m.Close()
+			return nil, err
+		}
+	}
+	return m, errors.Join(pingErrs...)
+}
+
+// Add register the group under name
+func (m *Manager) Add(name string, db *DB) error {
+	m.mu.Lock()
+	if _, ok := m.groups[name]; ok {
+		m.mu.Unlock()
+		return fmt.Errorf("duplicate group name %q", name)
+	}
+	m.groups[name] = db
+	m.mu.Unlock()
+
+	m.beatMu.Lock()
+	defer m.beatMu.Unlock()
+	if m.beating != nil {
+		db.managedBeat.Store(true)
+	}
+	return nil
+}
+
+// DB return the group registered under name, nil when there is no such group
+func (m *Manager) DB(name string) *DB {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.groups[name]
+}
+
+// Names return the sorted name of every group
+func (m *Manager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.groups))
+	for name := range m.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *Manager) each(fn func(name string, db *DB)) {
+	for _, name := range m.Names() {
+		if db := m.DB(name); db != nil {
+			fn(name, db)
+		}
+	}
+}
+
+// DoHeartBeat ping every group from a single goroutine, the groups must not run their own heartbeat
+func (m *Manager) DoHeartBeat() {
+	m.beatMu.Lock()
+	defer m.beatMu.Unlock()
+	if m.beating != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hb := &heartbeat{cancel: cancel, done: make(chan struct{})}
+	m.beating = hb
+	m.each(func(_ string, db *DB) {
+		db.managedBeat.Store(true)
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		m.tick(ctx, func(now time.Time) {
+			m.each(func(_ string, db *DB) {
+				db.beat(ctx, now)
+			})
+		})
+	}()
+	// down nodes of every group are probed from a single goroutine as well
+	go func() {
+		defer wg.Done()
+		m.tick(ctx, func(now time.Time) {
+			m.each(func(_ string, db *DB) {
+				db.probeDue(ctx, now)
+			})
+		})
+	}()
+	go func() {
+		wg.Wait()
+		close(hb.done)
+	}()
+}
+
+// tick call fn on the shortest heartbeat interval of the groups until ctx is done,
+// the interval is computed again after every tick so added groups are followed
+func (m *Manager) tick(ctx context.Context, fn func(now time.Time)) {
+	interval := m.beatInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			fn(now)
+			if i := m.beatInterval(); i != interval {
+				interval = i
+				ticker.Reset(interval)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// beatInterval return the shortest heartbeat interval of the groups
+func (m *Manager) beatInterval() time.Duration {
+	interval := defaultHeartbeatInterval
+	m.each(func(_ string, db *DB) {
+		if i := db.beatInterval(); i < interval {
+			interval = i
+		}
+	})
+	return interval
+}
+
+// StopBeat stop the shared heartbeat and wait for its goroutines to exit, it is a no-op
+// when the heartbeat is not running
+func (m *Manager) StopBeat() {
+	m.beatMu.Lock()
+	defer m.beatMu.Unlock()
+	if m.beating == nil {
+		return
+	}
+
+	m.beating.stop()
+	m.beating = nil
+	m.each(func(_ string, db *DB) {
+		db.managedBeat.Store(false)
+	})
+}
+
+// Status return the status of every group, keyed by group name
+func (m *Manager) Status() map[string][]DbStatus {
+	status := make(map[string][]DbStatus)
+	m.each(func(name string, db *DB) {
+		status[name], _ = db.GetStatus()
+	})
+	return status
+}
+
+// Stats return the connection pool statistics of every node, keyed by group and node name
+func (m *Manager) Stats() map[string]map[string]sql.DBStats {
+	stats := make(map[string]map[string]sql.DBStats)
+	m.each(func(name string, db *DB) {
+		stats[name] = db.Stats()
+	})
+	return stats
+}
+
+// AggregatedStats return the sum of connection pool statistics across all groups
+func (m *Manager) AggregatedStats() sql.DBStats {
+	var total sql.DBStats
+	m.each(func(_ string, db *DB) {
+		s := db.AggregatedStats()
+		total.MaxOpenConnections += s.MaxOpenConnections
+		total.OpenConnections += s.OpenConnections
+		total.InUse += s.InUse
+		total.Idle += s.Idle
+		total.WaitCount += s.WaitCount
+		total.WaitDuration += s.WaitDuration
+		total.MaxIdleClosed += s.MaxIdleClosed
+		total.MaxIdleTimeClosed += s.MaxIdleTimeClosed
+		total.MaxLifetimeClosed += s.MaxLifetimeClosed
+	})
+	return total
+}
+
+// StatusHandler return http handler which respond with the status of every group in JSON
+func (m *Manager) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := make(map[string]statusResponse)
+		m.each(func(name string, db *DB) {
+			status[name] = db.statusResponse()
+		})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	})
+}
+
+// Close every group, the error join every group error
+func (m *Manager) Close() error {
+	m.StopBeat()
+	var errs []error
+	m.each(func(name string, db *DB) {
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close group %s: %w", name, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// Shutdown gracefully close every group, see DB.Shutdown
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.StopBeat()
+	var errs []error
+	var mu sync.Mutex
+	wg := sync.WaitGroup{}
+	m.each(func(name string, db *DB) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := db.Shutdown(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("shutdown group %s: %w", name, err))
+				mu.Unlock()
+			}
+		}()
+	})
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,57 @@
+package sqlt
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// MapperFunc set the function mapping struct field names to column names on every node,
+// e.g. strings.ToLower or a snake_case conversion. Fields tagged with db are not mapped
+func (db *DB) MapperFunc(fn func(string) string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.mapper = reflectx.NewMapperFunc("db", fn)
+	db.recustomize()
+}
+
+// Unsafe mark every node unsafe, columns missing from the destination are ignored instead of
+// returning error. Unlike sqlx the DB itself is marked and returned
+func (db *DB) Unsafe() *DB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.unsafe = true
+	db.recustomize()
+	return db
+}
+
+// BindNamed bind the named query with arg using the bindvar of the driver
+func (db *DB) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	return db.nodeAt(db.master()).bindNamed(query, arg)
+}
+
+// recustomize replace the connection of every node with a customized copy sharing the same pool,
+// must be called with DB.mu held
+func (db *DB) recustomize() {
+	for _, n := range db.nodeList() {
+		n.conn.Store(db.customizeLocked(n.db()))
+	}
+}
+
+// customize return conn with the mapper and unsafe mode of the DB
+func (db *DB) customize(conn *sqlx.DB) *sqlx.DB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.customizeLocked(conn)
+}
+
+func (db *DB) customizeLocked(conn *sqlx.DB) *sqlx.DB {
+	if db.unsafe {
+		conn = conn.Unsafe()
+	}
+	if db.mapper != nil {
+		custom := *conn
+		custom.Mapper = db.mapper
+		conn = &custom
+	}
+	return conn
+}
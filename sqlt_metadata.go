@@ -0,0 +1,67 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"time"
+)
+
+// ResultMetadata describe the execution of the last routed call using the context
+type ResultMetadata struct {
+	// Node is the name of the node serving the call
+	Node string
+	Op   string
+	// Attempts is the number of times the query was sent
+	Attempts int
+	Duration time.Duration
+	// RowsReturned is the number of rows scanned by Select and Get
+	RowsReturned int64
+	// RowsAffected is reported by Exec when the driver support it
+	RowsAffected int64
+}
+
+type metadataKey struct{}
+
+// WithResultMetadata return a context which collect the execution metadata of the call using it,
+// the metadata is overwritten by every call so use a new context for each call
+func WithResultMetadata(ctx context.Context) (context.Context, *ResultMetadata) {
+	meta := new(ResultMetadata)
+	return context.WithValue(ctx, metadataKey{}, meta), meta
+}
+
+func resultMetadata(ctx context.Context) *ResultMetadata {
+	meta, _ := ctx.Value(metadataKey{}).(*ResultMetadata)
+	return meta
+}
+
+// recordMetadata fill the metadata of the call, if requested
+func recordMetadata(ctx context.Context, c call, n *node, d time.Duration, err error) {
+	meta := resultMetadata(ctx)
+	if meta == nil {
+		return
+	}
+	*meta = ResultMetadata{
+		Node:     n.name,
+		Op:       c.op,
+		Attempts: 1,
+		Duration: d,
+	}
+	if err == nil && c.dest != nil {
+		meta.RowsReturned = 1
+		if v := reflect.Indirect(reflect.ValueOf(c.dest)); v.Kind() == reflect.Slice {
+			meta.RowsReturned = int64(v.Len())
+		}
+	}
+}
+
+// recordAffected set the rows affected of the exec result, if requested
+func recordAffected(ctx context.Context, result sql.Result) {
+	meta := resultMetadata(ctx)
+	if meta == nil || result == nil {
+		return
+	}
+	if affected, err := result.RowsAffected(); err == nil {
+		meta.RowsAffected = affected
+	}
+}
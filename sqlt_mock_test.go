@@ -0,0 +1,167 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mockDriver answer every query with a row holding the DSN of the connection, so the tests can
+// assert which node served the query. A query with an int as first argument return that many rows.
+// Statements and transactions are recorded by DSN, statements containing "fail" fail
+type mockDriver struct{}
+
+// mockExec is a statement executed by a mock connection, or BEGIN, COMMIT and ROLLBACK
+type mockExec struct {
+	query string
+	args  int
+}
+
+var mockLog = struct {
+	sync.Mutex
+	execs map[string][]mockExec
+}{execs: make(map[string][]mockExec)}
+
+// mockExecs return the statements executed on the connections of the DSN
+func mockExecs(dsn string) []mockExec {
+	mockLog.Lock()
+	defer mockLog.Unlock()
+	return mockLog.execs[dsn]
+}
+
+func (c *mockConn) log(query string, args int) {
+	mockLog.Lock()
+	defer mockLog.Unlock()
+	mockLog.execs[c.dsn] = append(mockLog.execs[c.dsn], mockExec{query: query, args: args})
+}
+
+type (
+	mockConn struct{ dsn string }
+	mockStmt struct {
+		conn  *mockConn
+		query string
+	}
+	mockRows struct {
+		value string
+		left  int64
+	}
+)
+
+func init() {
+	sql.Register("sqltmock", mockDriver{})
+}
+
+func (mockDriver) Open(dsn string) (driver.Conn, error) { return &mockConn{dsn: dsn}, nil }
+
+func (c *mockConn) Prepare(query string) (driver.Stmt, error) {
+	return &mockStmt{conn: c, query: query}, nil
+}
+func (*mockConn) Close() error                { return nil }
+func (c *mockConn) Begin() (driver.Tx, error) { c.log("BEGIN", 0); return c, nil }
+func (c *mockConn) Commit() error             { c.log("COMMIT", 0); return nil }
+func (c *mockConn) Rollback() error           { c.log("ROLLBACK", 0); return nil }
+
+func (c *mockConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	rows := &mockRows{value: c.dsn, left: 1}
+	if len(args) > 0 {
+		if n, ok := args[0].Value.(int64); ok {
+			rows.left = n
+		}
+	}
+	return rows, nil
+}
+
+// ExecContext affect a row, or every row of a multi-row INSERT
+func (c *mockConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.log(query, len(args))
+	if strings.Contains(query, "fail") {
+		return nil, fmt.Errorf("mock: %s failed", query)
+	}
+	return driver.RowsAffected(1 + strings.Count(query, "), (")), nil
+}
+
+func (*mockStmt) Close() error  { return nil }
+func (*mockStmt) NumInput() int { return -1 }
+
+func (s *mockStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *mockStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+func (*mockRows) Columns() []string { return []string{"dsn"} }
+func (*mockRows) Close() error      { return nil }
+
+func (r *mockRows) Next(dest []driver.Value) error {
+	if r.left <= 0 {
+		return io.EOF
+	}
+	r.left--
+	dest[0] = r.value
+	return nil
+}
+
+// mockConns open a mock connection for every DSN
+func mockConns(t *testing.T, dsns ...string) []*sql.DB {
+	t.Helper()
+	conns := make([]*sql.DB, len(dsns))
+	for i, dsn := range dsns {
+		conn, err := sql.Open("sqltmock", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns[i] = conn
+	}
+	return conns
+}
+
+// newMockDB return a mocked DB with opts, the mock connection of every node is named after
+// the node, master, slave-1, slave-2...
+func newMockDB(t *testing.T, slaves int, opts ...Option) *DB {
+	t.Helper()
+	dsns := []string{"master"}
+	for i := 1; i <= slaves; i++ {
+		dsns = append(dsns, fmt.Sprintf("slave-%d", i))
+	}
+	return newMockDBWithDSN(t, dsns, opts...)
+}
+
+// newMockDBWithDSN return a mocked DB with opts, the first DSN is master
+func newMockDBWithDSN(t *testing.T, dsns []string, opts ...Option) *DB {
+	t.Helper()
+	// the log of a DSN start empty when the test run again
+	mockLog.Lock()
+	for _, dsn := range dsns {
+		delete(mockLog.execs, dsn)
+	}
+	mockLog.Unlock()
+	conns := mockConns(t, dsns...)
+	db := initMocking("sqltmock", newOptions(opts), conns[0], conns[1:]...)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// readNode return the DSN of the node serving a read
+func readNode(t *testing.T, db *DB) string {
+	t.Helper()
+	var dsn string
+	if err := db.Get(&dsn, "SELECT dsn"); err != nil {
+		t.Fatal(err)
+	}
+	return dsn
+}
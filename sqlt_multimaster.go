@@ -0,0 +1,46 @@
+package sqlt
+
+import "fmt"
+
+// WithMasters designate the writable nodes in priority order, e.g. the writers of a Galera or
+// Aurora multi-writer cluster. Writes go to the highest priority healthy master and fail over,
+// or back, on every ping. The other masters serve reads like slaves. WithMasters take precedence
+// over WithMasterElection
+func WithMasters(names ...string) Option {
+	return func(o *options) {
+		o.masters = names
+	}
+}
+
+// priorityMaster return the index of the highest priority master, the first master when none is healthy.
+// Must be called with DB.mu held
+func (db *DB) priorityMaster() (int, error) {
+	first := -1
+	for _, name := range db.opts.masters {
+		n, err := db.node(name)
+		if err != nil {
+			return 0, fmt.Errorf("master %q: %w", name, err)
+		}
+		if first < 0 {
+			first = n.index
+		}
+		if n.status.Connected && !n.disabled {
+			return n.index, nil
+		}
+	}
+	return first, nil
+}
+
+// failover move the writes to the highest priority healthy master, must be called with DB.mu held
+func (db *DB) failover() {
+	if len(db.opts.masters) == 0 {
+		return
+	}
+	master, err := db.priorityMaster()
+	if err != nil || master == db.masterIndex {
+		return
+	}
+	db.opts.logger.Printf("sqlt: master changed from %s to %s", db.nodeAt(db.masterIndex).name, db.nodeAt(master).name)
+	db.masterIndex = master
+	db.recordEvent(db.nodeAt(master), EventMaster, nil)
+}
@@ -0,0 +1,195 @@
+package sqlt
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DSNInfo is the address of a node parsed from its DSN
+type DSNInfo struct {
+	Host     string `json:"host,omitempty"`
+	Port     string `json:"port,omitempty"`
+	Database string `json:"database,omitempty"`
+}
+
+// WithNodeNameFormat set the format of the name of nodes without explicit name, default to "master"
+// and "slave-{index}". The format can use {role}, {index}, {host}, {port} and {database},
+// e.g. "{role} {host}:{port}/{database}" name a node "slave db-ro-2.internal:5432/orders"
+func WithNodeNameFormat(format string) Option {
+	return func(o *options) {
+		o.nameFormat = format
+	}
+}
+
+// nodeName return the name of the node at index i without explicit name
+func (db *DB) nodeName(i int, dsn string) string {
+	role := "master"
+	if i > 0 {
+		role = "slave"
+	}
+	format := db.opts.nameFormat
+	if format == "" {
+		if i == 0 {
+			return role
+		}
+		format = "{role}-{index}"
+	}
+
+	info := parseDSN(dsn)
+	return strings.NewReplacer(
+		"{role}", role,
+		"{index}", strconv.Itoa(i),
+		"{host}", info.Host,
+		"{port}", info.Port,
+		"{database}", info.Database,
+	).Replace(format)
+}
+
+// setDSN set the configured source of the node and parse its address
+func (n *node) setDSN(dsn string) {
+	n.dsn = dsn
+	n.info = parseDSN(dsn)
+}
+
+var mysqlAddrRegexp = regexp.MustCompile(`@(tcp6?)?\(([^)]+)\)`)
+
+// parseDSN parse the host, port and database of URL, go-sql-driver/mysql and key/value DSN,
+// unknown formats return an empty DSNInfo. The first host is used for multi-host DSN
+func parseDSN(dsn string) DSNInfo {
+	switch {
+	case strings.Contains(dsn, "://"):
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return DSNInfo{}
+		}
+		info := DSNInfo{Database: strings.TrimPrefix(u.Path, "/")}
+		if hosts := urlHosts(dsn); len(hosts) > 0 {
+			info.Host, info.Port = splitHostPort(hosts[0])
+		}
+		return info
+
+	case mysqlAddrRegexp.MatchString(dsn):
+		match := mysqlAddrRegexp.FindStringSubmatchIndex(dsn)
+		var info DSNInfo
+		info.Host, info.Port = splitHostPort(dsn[match[4]:match[5]])
+		if rest, ok := strings.CutPrefix(dsn[match[1]:], "/"); ok {
+			info.Database, _, _ = strings.Cut(rest, "?")
+		}
+		return info
+	}
+
+	params := parseKeyValue(dsn)
+	if params == nil {
+		return DSNInfo{}
+	}
+	host, _, _ := strings.Cut(params["host"], ",")
+	port, _, _ := strings.Cut(params["port"], ",")
+	return DSNInfo{Host: host, Port: port, Database: params["dbname"]}
+}
+
+// dsnHosts return every host of the DSN without port, in the order of the DSN
+func dsnHosts(dsn string) []string {
+	var hosts []string
+	switch {
+	case strings.Contains(dsn, "://"):
+		for _, hostport := range urlHosts(dsn) {
+			host, _ := splitHostPort(hostport)
+			hosts = append(hosts, host)
+		}
+	case mysqlAddrRegexp.MatchString(dsn):
+		match := mysqlAddrRegexp.FindStringSubmatch(dsn)
+		host, _ := splitHostPort(match[2])
+		hosts = append(hosts, host)
+	default:
+		if host := parseKeyValue(dsn)["host"]; host != "" {
+			hosts = strings.Split(host, ",")
+		}
+	}
+	return hosts
+}
+
+// urlHosts return the host:port list of the URL authority, url.Parse keep a multi-host
+// authority such as "h1:5432,h2:5433" as a single host
+func urlHosts(dsn string) []string {
+	_, rest, _ := strings.Cut(dsn, "://")
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		rest = rest[:i]
+	}
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		rest = rest[i+1:]
+	}
+	if rest == "" {
+		return nil
+	}
+	return strings.Split(rest, ",")
+}
+
+func splitHostPort(hostport string) (string, string) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return strings.Trim(hostport, "[]"), ""
+	}
+	return host, port
+}
+
+func joinHostPort(host, port string) string {
+	if port == "" {
+		if strings.Contains(host, ":") {
+			return "[" + host + "]"
+		}
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// parseKeyValue parse a libpq key/value DSN, values can be single quoted with backslash escapes,
+// e.g. host='db 1' password='it\'s'. Nil is returned when dsn is not a key/value DSN
+func parseKeyValue(dsn string) map[string]string {
+	params := make(map[string]string)
+	for s := strings.TrimSpace(dsn); s != ""; s = strings.TrimSpace(s) {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil
+		}
+		key := strings.TrimSpace(s[:eq])
+		if strings.ContainsAny(key, " \t\n'") {
+			return nil
+		}
+		s = strings.TrimLeft(s[eq+1:], " \t\n")
+
+		var val strings.Builder
+		if strings.HasPrefix(s, "'") {
+			i, closed := 1, false
+			for ; i < len(s); i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				} else if s[i] == '\'' {
+					closed = true
+					break
+				}
+				val.WriteByte(s[i])
+			}
+			if !closed {
+				return nil
+			}
+			s = s[i+1:]
+		} else {
+			i := 0
+			for ; i < len(s) && !strings.ContainsRune(" \t\n", rune(s[i])); i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				val.WriteByte(s[i])
+			}
+			s = s[i:]
+		}
+		params[key] = val.String()
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
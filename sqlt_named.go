@@ -0,0 +1,79 @@
+package sqlt
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// NamedQuery bind the named query with arg and query using slave
+func (db *DB) NamedQuery(query string, arg interface{}) (*sqlx.Rows, error) {
+	return db.NamedQueryContext(context.Background(), query, arg)
+}
+
+// NamedQueryContext bind the named query with arg and query using slave
+func (db *DB) NamedQueryContext(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error) {
+	return db.namedQuery(ctx, call{op: "NamedQuery", query: query, args: []interface{}{arg}, stream: true}, arg)
+}
+
+// NamedQueryMasterContext bind the named query with arg and query using master
+func (db *DB) NamedQueryMasterContext(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error) {
+	return db.namedQuery(ctx, call{op: "NamedQueryMaster", query: query, args: []interface{}{arg}, master: true, stream: true}, arg)
+}
+
+func (db *DB) namedQuery(ctx context.Context, c call, arg interface{}) (*sqlx.Rows, error) {
+	var rows *sqlx.Rows
+	err := db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+		query, args, err := n.bindNamed(query, arg)
+		if err != nil {
+			return err
+		}
+		rows, err = db.conn(ctx, n).QueryxContext(ctx, query, args...)
+		if err == nil {
+			db.leaks.track(c, n, rows.Rows)
+		}
+		return err
+	})
+	return rows, err
+}
+
+// NamedGet bind the named query with arg and get a single row using slave
+func (db *DB) NamedGet(dest interface{}, query string, arg interface{}) error {
+	return db.NamedGetContext(context.Background(), dest, query, arg)
+}
+
+// NamedGetContext bind the named query with arg and get a single row using slave
+func (db *DB) NamedGetContext(ctx context.Context, dest interface{}, query string, arg interface{}) error {
+	return db.namedRead(ctx, call{op: "NamedGet", query: query, args: []interface{}{arg}, dest: dest}, arg, (*sqlx.DB).GetContext)
+}
+
+// NamedGetMasterContext bind the named query with arg and get a single row using master
+func (db *DB) NamedGetMasterContext(ctx context.Context, dest interface{}, query string, arg interface{}) error {
+	return db.namedRead(ctx, call{op: "NamedGetMaster", query: query, args: []interface{}{arg}, dest: dest, master: true}, arg, (*sqlx.DB).GetContext)
+}
+
+// NamedSelect bind the named query with arg and select rows using slave
+func (db *DB) NamedSelect(dest interface{}, query string, arg interface{}) error {
+	return db.NamedSelectContext(context.Background(), dest, query, arg)
+}
+
+// NamedSelectContext bind the named query with arg and select rows using slave
+func (db *DB) NamedSelectContext(ctx context.Context, dest interface{}, query string, arg interface{}) error {
+	return db.namedRead(ctx, call{op: "NamedSelect", query: query, args: []interface{}{arg}, dest: dest}, arg, (*sqlx.DB).SelectContext)
+}
+
+// NamedSelectMasterContext bind the named query with arg and select rows using master
+func (db *DB) NamedSelectMasterContext(ctx context.Context, dest interface{}, query string, arg interface{}) error {
+	return db.namedRead(ctx, call{op: "NamedSelectMaster", query: query, args: []interface{}{arg}, dest: dest, master: true}, arg, (*sqlx.DB).SelectContext)
+}
+
+// namedRead bind the named query with the bindvar of the node and read it into dest
+func (db *DB) namedRead(ctx context.Context, c call, arg interface{}, read func(*sqlx.DB, context.Context, interface{}, string, ...interface{}) error) error {
+	return db.runRead(ctx, c, func(ctx context.Context, n *node, query string, dest interface{}) error {
+		bound, args, err := n.bindNamed(query, arg)
+		if err != nil {
+			return err
+		}
+		return read(n.db(), ctx, dest, bound, args...)
+	})
+}
@@ -0,0 +1,518 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// node is a single database connection inside the group
+type node struct {
+	index   int
+	driver  string
+	dialect Dialect
+	// bind is the bindvar type of the driver, see setDriver
+	bind int
+	// info is parsed from the configured source
+	info  DSNInfo
+	name  string
+	tags  map[string]string
+	label string
+	// conn is swapped when the node is reopened, dsn is the configured source
+	conn     atomic.Pointer[sqlx.DB]
+	dsn      string
+	address  string
+	reopenMu sync.Mutex
+
+	// query counters
+	queries atomic.Uint64
+	errors  atomic.Uint64
+	// exponentially weighted moving average of query latency in nanoseconds, stored as float64 bits
+	latency atomic.Uint64
+	// recent query latencies and time of the last query error in unix nanoseconds, for the status
+	window    latencyWindow
+	lastError atomic.Int64
+	// heartbeat interval of the node and time of the last heartbeat ping, only used by the heartbeat goroutine
+	pingInterval time.Duration
+	lastPing     time.Time
+	// backoff of the probes while the node is down, in nanoseconds
+	backoff atomic.Int64
+	// nextProbe of the down node in unix nanoseconds, zero while the node is up
+	nextProbe atomic.Int64
+	// lag of the slave in nanoseconds measured by the LagProbe, -1 when unknown
+	lag atomic.Int64
+	// replayed cache the session positions the slave replayed, see WithConsistency
+	replayed replayCache
+	// limit is the semaphore of WithMaxInflight, nil when unlimited
+	limit    chan struct{}
+	inflight atomic.Int64
+	// fault injected by FailNode and DelayNode
+	fault atomic.Pointer[fault]
+	// discoveredBy name the discovery which added the node, removed once the discovery dropped the node
+	discoveredBy string
+	removed      atomic.Bool
+	// server version is only queried once
+	versionOnce sync.Once
+
+	// guarded by DB.mu
+	pool     poolConfig
+	weight   int
+	active   bool
+	disabled bool
+	status   DbStatus
+	metadata map[string]string
+	// consecutive ping results, see HealthThresholds
+	checked   bool
+	failures  int
+	successes int
+}
+
+func newNode(index int, name string, db *sqlx.DB) *node {
+	n := &node{
+		index:  index,
+		name:   name,
+		weight: 1,
+		active: true,
+		status: DbStatus{
+			Name:       name,
+			Connected:  true,
+			LastActive: time.Now().String(),
+		},
+	}
+	n.conn.Store(db)
+	n.lag.Store(-1)
+	return n
+}
+
+// db return the current connection pool of the node
+func (n *node) db() *sqlx.DB {
+	return n.conn.Load()
+}
+
+// poolConfig is remembered so it can be applied again when the node is reopened
+type poolConfig struct {
+	maxOpen     int
+	maxIdle     *int
+	maxLifetime time.Duration
+	maxIdleTime time.Duration
+}
+
+func (p poolConfig) apply(db *sqlx.DB) {
+	db.SetMaxOpenConns(p.maxOpen)
+	if p.maxIdle != nil {
+		db.SetMaxIdleConns(*p.maxIdle)
+	}
+	setConnMaxLifetime(db, p.maxLifetime)
+	db.SetConnMaxIdleTime(p.maxIdleTime)
+}
+
+// routing is an immutable snapshot of the nodes serving traffic, a new snapshot
+// is swapped in on every health change so selecting a node never need a lock
+type routing struct {
+	master int
+	// noMaster is set when the master is down or no node is writable
+	noMaster bool
+	slaves   []*node
+	skipped  []string
+	weights  []int
+	// cumulative weight of slaves, used by weighted round-robin
+	cumulative []uint64
+	total      uint64
+	// preferred is the routing of slaves matching the read preference tags
+	preferred *routing
+	// single is set when master is the only node, mixedBind when the nodes use different bindvars
+	single    bool
+	mixedBind bool
+}
+
+// updateRouting build a new routing snapshot from the current nodes state, must be called with DB.mu held
+func (db *DB) updateRouting() {
+	r := db.buildRouting(nil)
+	if tags := db.opts.readPreference.Tags; len(tags) > 0 {
+		r.preferred = db.buildRouting(func(n *node) bool {
+			return matchTags(n.tags, tags)
+		})
+	}
+	r.noMaster = db.masterLost || !db.nodeAt(r.master).active
+	r.single = len(db.members()) == 1
+	r.mixedBind = db.bindvarsDiffer()
+	if r.preferred != nil {
+		r.preferred.noMaster = r.noMaster
+	}
+	if !r.noMaster && db.masterWait != nil {
+		close(db.masterWait)
+		db.masterWait = nil
+	}
+	db.route.Store(r)
+}
+
+// buildRouting build the routing of active slaves accepted by filter, nil filter accept every slave
+func (db *DB) buildRouting(filter func(n *node) bool) *routing {
+	r := &routing{master: db.masterIndex}
+	for _, n := range db.members() {
+		if n.index == r.master || (db.readLabel != "" && n.label != db.readLabel) {
+			continue
+		}
+		if filter != nil && !filter(n) {
+			continue
+		}
+		if !n.active || n.disabled {
+			r.skipped = append(r.skipped, n.name)
+			continue
+		}
+		r.total += uint64(n.weight)
+		r.slaves = append(r.slaves, n)
+		r.weights = append(r.weights, n.weight)
+		r.cumulative = append(r.cumulative, r.total)
+	}
+	return r
+}
+
+// master return the index of node serving writes
+func (db *DB) master() int {
+	return db.route.Load().master
+}
+
+// slave return the node serving the next read, fallback to master when no slave is active
+func (db *DB) slave() *node {
+	n, _ := db.secondary(SecondaryPreferred)
+	return n
+}
+
+// secondary return the slave serving the next read, master is returned with
+// ErrAllReplicasDown in Secondary mode when no slave is active
+func (db *DB) secondary(mode ReadMode) (*node, error) {
+	r := db.route.Load()
+	if p := r.preferred; p != nil && (p.total > 0 || !db.opts.readPreference.Fallback) {
+		r = p
+	}
+	if r.total == 0 {
+		if mode == Secondary {
+			return db.nodeAt(r.master), ErrAllReplicasDown
+		}
+		return db.nodeAt(r.master), nil
+	}
+	// master serve a share of reads when configured
+	if ratio := db.opts.masterReadRatio; ratio > 0 && mode != Secondary && !r.noMaster && db.random.Float64() < ratio {
+		return db.nodeAt(r.master), nil
+	}
+	return db.balancer.pick(r), nil
+}
+
+// track count the query result of the node, a canceled query, e.g. the losing read of a hedge,
+// is not an error of the node
+func (n *node) track(err error) error {
+	n.queries.Add(1)
+	if err != nil && err != sql.ErrNoRows && !errors.Is(err, context.Canceled) {
+		n.errors.Add(1)
+		n.lastError.Store(time.Now().UnixNano())
+	}
+	return err
+}
+
+// observe record the latency of a query served by the node
+func (db *DB) observe(n *node, d time.Duration) {
+	n.window.record(d)
+	db.balancer.observe(n, d)
+}
+
+// nodeList return every node, a node is only added or replaced with DB.mu held
+func (db *DB) nodeList() []*node {
+	if list := db.nodes.Load(); list != nil {
+		return *list
+	}
+	return nil
+}
+
+// members return every node which is not removed by the discovery
+func (db *DB) members() []*node {
+	nodes := db.nodeList()
+	members := make([]*node, 0, len(nodes))
+	for _, n := range nodes {
+		if !n.removed.Load() {
+			members = append(members, n)
+		}
+	}
+	return members
+}
+
+// nodeAt return the node at index i, the index of a node never change but the nodes following
+// a node removed by the discovery are shifted in the list. Only master is looked up by index,
+// slaves are picked by pointer so a removed node is never looked up
+func (db *DB) nodeAt(i int) *node {
+	list := db.nodeList()
+	if i < len(list) && list[i].index == i {
+		return list[i]
+	}
+	return list[sort.Search(len(list), func(j int) bool { return list[j].index >= i })]
+}
+
+// appendNode add the node into a copy of the list, must be called with DB.mu held or before the DB is shared
+func (db *DB) appendNode(n *node) {
+	current := db.nodeList()
+	list := make([]*node, len(current), len(current)+1)
+	copy(list, current)
+	list = append(list, n)
+	db.nodes.Store(&list)
+	db.nextIndex = n.index + 1
+}
+
+// removeNodes drop the nodes from a copy of the list, must be called with DB.mu held
+func (db *DB) removeNodes(removed []*node) {
+	current := db.nodeList()
+	list := make([]*node, 0, len(current))
+	for _, n := range current {
+		if !slices.Contains(removed, n) {
+			list = append(list, n)
+		}
+	}
+	db.nodes.Store(&list)
+}
+
+// retireInterval is the interval between the checks of the queries still running on a removed node
+const retireInterval = 10 * time.Millisecond
+
+// retire close the pool of the removed node once its queries and streamed rows are done
+func (db *DB) retire(n *node) {
+	for n.inflight.Load() > 0 {
+		time.Sleep(retireInterval)
+	}
+	n.db().Close()
+}
+
+// node return the node with the given name
+func (db *DB) node(name string) (*node, error) {
+	for _, n := range db.nodeList() {
+		if n.name == name && !n.removed.Load() {
+			return n, nil
+		}
+	}
+	return nil, ErrNodeNotFound
+}
+
+// SetWeight set the share of reads served by the node relative to other slaves
+func (db *DB) SetWeight(name string, weight int) error {
+	if weight < 1 {
+		return fmt.Errorf("invalid weight %d", weight)
+	}
+	n, err := db.node(name)
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	n.weight = weight
+	db.updateRouting()
+	return nil
+}
+
+// Disable take the slave out of rotation, e.g. for maintenance, the heartbeat keep reporting
+// its connectivity. Master can't be disabled
+func (db *DB) Disable(name string) error {
+	return db.setDisabled(name, true)
+}
+
+// Enable put the disabled slave back into rotation
+func (db *DB) Enable(name string) error {
+	return db.setDisabled(name, false)
+}
+
+func (db *DB) setDisabled(name string, disabled bool) error {
+	n, err := db.node(name)
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if disabled && n.index == db.masterIndex {
+		return fmt.Errorf("node %q is master", name)
+	}
+	if n.disabled != disabled {
+		event := EventEnabled
+		if disabled {
+			event = EventDisabled
+		}
+		db.recordEvent(n, event, nil)
+	}
+	n.disabled = disabled
+	db.updateRouting()
+	return nil
+}
+
+// SetNodeMetadata attach user defined metadata to the node, the metadata is reported in the node status
+func (db *DB) SetNodeMetadata(name, key, value string) error {
+	n, err := db.node(name)
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if n.metadata == nil {
+		n.metadata = make(map[string]string)
+	}
+	n.metadata[key] = value
+	return nil
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	c := make(map[string]string, len(labels))
+	for k, v := range labels {
+		c[k] = v
+	}
+	return c
+}
+
+// healthCheck is the result of a single node check
+type healthCheck struct {
+	backend  string
+	version  string
+	writable bool
+	// lag is -1 when not measured
+	lag time.Duration
+	// latency of the ping, fed to the balancer so a node serving no read keeps being measured
+	latency time.Duration
+	err     error
+}
+
+// isLoadBalancer return true if the node point to an external load balancer
+func (db *DB) isLoadBalancer(n *node) bool {
+	_, ok := db.opts.loadBalancers[n.name]
+	return ok
+}
+
+// pingNode check the node connection, load balancer with backend query
+// also report which backend answered the health check
+func (db *DB) pingNode(ctx context.Context, n *node) healthCheck {
+	check := healthCheck{lag: -1}
+	if check.err = n.injected(ctx); check.err != nil {
+		return check
+	}
+
+	start := time.Now()
+	lb, ok := db.opts.loadBalancers[n.name]
+	switch {
+	case ok && lb.BackendQuery != "":
+		check.err = n.db().QueryRowContext(ctx, lb.BackendQuery).Scan(&check.backend)
+	case db.opts.healthCheck.Query != "":
+		check.err = db.opts.healthCheck.run(ctx, n.db(), n.index == db.master())
+	default:
+		check.err = n.db().PingContext(ctx)
+	}
+
+	if check.err == nil {
+		check.latency = time.Since(start)
+	}
+	if check.err == nil && db.opts.election != nil {
+		check.writable = db.checkWritable(ctx, n)
+	}
+	if check.err == nil && db.opts.lagProbe != nil && n.index != db.master() {
+		check.lag = db.measureLag(ctx, n)
+	}
+	if check.err == nil {
+		n.versionOnce.Do(func() {
+			// server version is informational, error is ignored
+			n.db().QueryRowContext(ctx, db.versionQuery(n)).Scan(&check.version)
+		})
+	}
+	return check
+}
+
+// versionQuery return the query to retrieve server version
+func (db *DB) versionQuery(n *node) string {
+	switch n.dialect {
+	case DialectSQLite:
+		return "SELECT sqlite_version()"
+	case DialectSQLServer:
+		return "SELECT @@VERSION"
+	}
+	return "SELECT version()"
+}
+
+// setHealth apply the check result to the node, must be called with DB.mu held
+func (db *DB) setHealth(n *node, check healthCheck) {
+	apply := db.count(n, check)
+	if check.err != nil {
+		n.status.Error = errors.New(n.name + ": " + check.err.Error())
+		if !apply {
+			return
+		}
+		if n.status.Connected {
+			db.opts.logger.Printf("sqlt: node %s is down: %v", n.name, check.err)
+			db.recordEvent(n, EventDown, check.err)
+		}
+		n.status.Connected = false
+		n.lag.Store(-1)
+		n.scheduleProbe(time.Now())
+		// load balancer is never evicted, it route around its own bad backends
+		if !db.isLoadBalancer(n) {
+			n.active = false
+		}
+		return
+	}
+
+	if !apply {
+		return
+	}
+	if !n.status.Connected {
+		db.opts.logger.Printf("sqlt: node %s is up", n.name)
+		db.recordEvent(n, EventUp, nil)
+	}
+	n.active = true
+	n.nextProbe.Store(0)
+	if check.latency > 0 {
+		db.balancer.observe(n, check.latency)
+	}
+	n.lag.Store(int64(check.lag))
+	n.status.Connected = true
+	n.status.LastActive = time.Now().Format(time.RFC1123)
+	n.status.Error = nil
+	if check.backend != "" {
+		n.status.Backend = check.backend
+	}
+	if check.version != "" {
+		n.status.ServerVersion = check.version
+	}
+}
+
+// beatInterval return the heartbeat tick, which is the shortest interval of every node
+func (db *DB) beatInterval() time.Duration {
+	interval := defaultHeartbeatInterval
+	for _, n := range db.nodeList() {
+		if n.pingInterval > 0 && n.pingInterval < interval {
+			interval = n.pingInterval
+		}
+	}
+	return interval
+}
+
+// dueNodes return the nodes which heartbeat interval has elapsed at now, down nodes are
+// left to the probe loop
+func (db *DB) dueNodes(now time.Time) []*node {
+	var due []*node
+	for _, n := range db.members() {
+		if n.nextProbe.Load() != 0 {
+			continue
+		}
+		// allow a small jitter so the node is not skipped by a tick arriving slightly early
+		interval := n.pingInterval
+		if now.Sub(n.lastPing) >= interval-interval/10 {
+			n.lastPing = now
+			due = append(due, n)
+		}
+	}
+	return due
+}
@@ -0,0 +1,90 @@
+package sqlt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestRoutingFollowHealth(t *testing.T) {
+	errDown := errors.New("node down")
+
+	tests := []struct {
+		name string
+		down []string
+		want []string
+	}{
+		{name: "all slaves up", want: []string{"slave-1", "slave-2", "slave-3"}},
+		{name: "one slave down", down: []string{"slave-2"}, want: []string{"slave-1", "slave-3"}},
+		{name: "two slaves down", down: []string{"slave-1", "slave-3"}, want: []string{"slave-2"}},
+		{name: "every slave down", down: []string{"slave-1", "slave-2", "slave-3"}, want: []string{"master"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conns := mockConns(t, "master", "slave-1", "slave-2", "slave-3")
+			db := InitMockingWithConns(conns[0], conns[1:]...)
+			defer db.Close()
+
+			for _, name := range tt.down {
+				if err := db.FailNode(name, errDown); err != nil {
+					t.Fatal(err)
+				}
+			}
+			db.Ping()
+
+			got := make(map[string]int)
+			for i := 0; i < len(tt.want)*10; i++ {
+				got[readNode(t, db)]++
+			}
+			for _, name := range tt.want {
+				if got[name] != 10 {
+					t.Fatalf("reads %v, want 10 reads on each of %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRoutingConcurrentPing(t *testing.T) {
+	conns := mockConns(t, "master", "slave-1", "slave-2", "slave-3")
+	db := InitMockingWithConns(conns[0], conns[1:]...)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				// a read racing the ping may be routed to the failed node right before it is ejected
+				var dsn string
+				if err := db.Get(&dsn, "SELECT dsn"); err == nil && dsn != "slave-1" && dsn != "slave-2" && dsn != "slave-3" {
+					t.Errorf("read served by %s", dsn)
+					return
+				}
+				db.GetStatus()
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		db.FailNode("slave-2", errors.New("node down"))
+		db.Ping()
+		db.RecoverNode("slave-2")
+		db.Ping()
+	}
+	cancel()
+	wg.Wait()
+
+	status, err := db.GetStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if !s.Connected {
+			t.Fatalf("node %s not connected after recovery", s.Name)
+		}
+	}
+}
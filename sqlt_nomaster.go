@@ -0,0 +1,65 @@
+package sqlt
+
+import (
+	"context"
+	"time"
+)
+
+// NoMasterPolicy decide what happen to writes while there is no writable master, e.g. during failover.
+// Reads keep being served by the slaves. Without the policy writes are sent to the last known master
+type NoMasterPolicy struct {
+	// QueueSize is the maximum number of writes waiting for the master, zero fail the writes fast
+	QueueSize int
+	// QueueTimeout is the maximum time a write wait for the master, default to 5 seconds
+	QueueTimeout time.Duration
+}
+
+const defaultQueueTimeout = time.Second * 5
+
+// WithNoMasterPolicy fail or queue the writes with ErrNoMaster while the master is down or
+// no node is writable, instead of sending them to an unavailable node
+func WithNoMasterPolicy(policy NoMasterPolicy) Option {
+	return func(o *options) {
+		if policy.QueueTimeout <= 0 {
+			policy.QueueTimeout = defaultQueueTimeout
+		}
+		o.noMaster = &policy
+		o.pipeline = true
+	}
+}
+
+// waitMaster return nil when the master is available, otherwise queue the caller until
+// the master is back based on the no master policy
+func (db *DB) waitMaster(ctx context.Context) error {
+	policy := db.opts.noMaster
+	if policy == nil || !db.route.Load().noMaster {
+		return nil
+	}
+	if db.queued.Add(1) > int64(policy.QueueSize) {
+		db.queued.Add(-1)
+		return ErrNoMaster
+	}
+	defer db.queued.Add(-1)
+
+	db.mu.Lock()
+	if !db.route.Load().noMaster {
+		db.mu.Unlock()
+		return nil
+	}
+	if db.masterWait == nil {
+		db.masterWait = make(chan struct{})
+	}
+	wait := db.masterWait
+	db.mu.Unlock()
+
+	timer := time.NewTimer(policy.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case <-wait:
+		return nil
+	case <-timer.C:
+		return ErrNoMaster
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,194 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+)
+
+// Option configure the DB when opening the connection
+type Option func(*options)
+
+type options struct {
+	loadBalancers    map[string]LoadBalancerConfig
+	reconnectTimeout time.Duration
+	pingTimeout      time.Duration
+	cacheHooks       CacheHooks
+	tags             map[string]map[string]string
+	statusFields     map[string]string
+	balancer         func(random *randSource) balancer
+	seed             *uint64
+	resolver         Resolver
+	masterReadRatio  float64
+	idempotencyStore IdempotencyStore
+	healthCheck      HealthCheck
+	election         *MasterElection
+	labels           map[string]string
+	pools            map[string]PoolConfig
+	noMaster         *NoMasterPolicy
+	pingIntervals    map[string]time.Duration
+	stmtCacheSize    int
+	onConnect        OnConnectFunc
+	credentials      CredentialProvider
+	queryTimeout     time.Duration
+	queryTimeouts    map[string]time.Duration
+	queryComments    bool
+	thresholds       HealthThresholds
+	minHealthy       *int
+	requireMaster    *bool
+	maxInflight      map[string]int
+	cache            CacheStore
+	cacheTTL         time.Duration
+	cacheLagWindow   time.Duration
+	masters          []string
+	writeRetry       RetryPolicy
+	watchdog         *Watchdog
+	leakDetector     *LeakDetector
+	shadow           *Shadow
+	decorators       []func(ctx context.Context) context.Context
+	historySize      int
+	queryStats       bool
+	openRetry        RetryPolicy
+	driverWrapper    func(driver.Driver) driver.Driver
+	nameFormat       string
+	lagProbe         LagProbe
+	logger           Logger
+	slowQuery        time.Duration
+	redactArgs       bool
+	readYourWrites   ReadYourWrites
+	hedgeDelay       time.Duration
+	readPreference   ReadPreference
+	verbRouting      bool
+	discovery        *Discovery
+	replicaDiscovery *ReplicaDiscovery
+	readLabel        string
+	writeLabel       string
+	// pipeline is set by the options which need the full pipeline of DB.run for every call, see runSingle
+	pipeline bool
+}
+
+const (
+	defaultReconnectTimeout = time.Second * 5
+	defaultPingTimeout      = time.Second * 2
+	// defaultHeartbeatInterval is the heartbeat interval of every node without override
+	defaultHeartbeatInterval = time.Second * 2
+)
+
+func newOptions(opts []Option) options {
+	o := options{
+		loadBalancers:    make(map[string]LoadBalancerConfig),
+		tags:             make(map[string]map[string]string),
+		labels:           make(map[string]string),
+		pools:            make(map[string]PoolConfig),
+		pingIntervals:    make(map[string]time.Duration),
+		queryTimeouts:    make(map[string]time.Duration),
+		maxInflight:      make(map[string]int),
+		reconnectTimeout: defaultReconnectTimeout,
+		pingTimeout:      defaultPingTimeout,
+		balancer:         func(*randSource) balancer { return &roundRobin{} },
+		logger:           nopLogger{},
+		idempotencyStore: TableStore{Table: "sqlt_idempotency"},
+		historySize:      defaultHistorySize,
+		cacheLagWindow:   defaultCacheLagWindow,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// LoadBalancerConfig describe a replica DSN which point to an external load balancer
+type LoadBalancerConfig struct {
+	// Weight is the share of reads routed to the load balancer compared to a plain slave
+	Weight int
+	// BackendQuery is used as health check instead of ping, it must return a single column
+	// identifying the backend that answered, e.g. "SELECT @@hostname" or "SELECT inet_server_addr()"
+	BackendQuery string
+}
+
+// WithLoadBalancer mark the node as an external load balancer, the node is never evicted from
+// the slave rotation because the load balancer is responsible to route around its own bad backends
+func WithLoadBalancer(name string, config LoadBalancerConfig) Option {
+	return func(o *options) {
+		if config.Weight < 1 {
+			config.Weight = 1
+		}
+		o.loadBalancers[name] = config
+	}
+}
+
+// WithReconnectTimeout set the timeout of every reconnect attempt to an inactive node, default to 5 seconds
+func WithReconnectTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.reconnectTimeout = d
+		}
+	}
+}
+
+// WithPingTimeout set the timeout of every ping to an active node, default to 2 seconds
+func WithPingTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.pingTimeout = d
+		}
+	}
+}
+
+// WithPingInterval override the heartbeat interval of the node, e.g. check a cross-region
+// replica every 30 seconds while local nodes are checked every 2 seconds
+func WithPingInterval(name string, d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.pingIntervals[name] = d
+		}
+	}
+}
+
+// WithNodeTags attach tags to the node, e.g. {"region": "us-east-1", "az": "us-east-1a"}
+func WithNodeTags(name string, tags map[string]string) Option {
+	return func(o *options) {
+		o.tags[name] = copyLabels(tags)
+	}
+}
+
+// WithStatusFieldNames rename the JSON fields of node status served by StatusHandler,
+// the key is the default field name, e.g. {"last_active": "lastActive"}
+func WithStatusFieldNames(names map[string]string) Option {
+	return func(o *options) {
+		o.statusFields = names
+	}
+}
+
+// WithLatencyBalancer route reads using the exponentially weighted moving average of query latency,
+// two random slaves are compared on every read and the faster one is picked. Decay is the weight
+// of the newest sample between 0 and 1, default to 0.3
+func WithLatencyBalancer(decay float64) Option {
+	return func(o *options) {
+		if decay <= 0 || decay > 1 {
+			decay = defaultLatencyDecay
+		}
+		o.balancer = func(random *randSource) balancer { return &latencyBalancer{decay: decay, random: random} }
+	}
+}
+
+// WithMasterReadRatio let the master serve the given share of reads between 0 and 1,
+// e.g. 0.2 route one of five reads to master. Useful for small deployments with idle master
+func WithMasterReadRatio(ratio float64) Option {
+	return func(o *options) {
+		if ratio < 0 {
+			ratio = 0
+		} else if ratio > 1 {
+			ratio = 1
+		}
+		o.masterReadRatio = ratio
+	}
+}
+
+// WithHealthCheck use the query instead of ping to decide whether a node is healthy,
+// used by heartbeat and GetStatus
+func WithHealthCheck(check HealthCheck) Option {
+	return func(o *options) {
+		o.healthCheck = check
+	}
+}
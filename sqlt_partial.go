@@ -0,0 +1,49 @@
+package sqlt
+
+// WithMinHealthyReplicas let Open succeed when at least n slaves and master connect,
+// the failed nodes are reported in the status and brought back by the heartbeat
+func WithMinHealthyReplicas(n int) Option {
+	return func(o *options) {
+		o.minHealthy = &n
+	}
+}
+
+// WithRequireMaster set whether Open need the master to connect when tolerating failed nodes,
+// default to true. WithRequireMaster(false) alone let Open succeed with any reachable node
+func WithRequireMaster(require bool) Option {
+	return func(o *options) {
+		o.requireMaster = &require
+	}
+}
+
+// tolerate return nil when the ping error of Open is within the configured tolerance
+func (db *DB) tolerate(err error) error {
+	if err == nil || (db.opts.minHealthy == nil && db.opts.requireMaster == nil) {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	healthy, masterHealthy := 0, false
+	for _, n := range db.members() {
+		switch {
+		case n.index == db.masterIndex:
+			masterHealthy = n.status.Connected
+		case n.status.Connected:
+			healthy++
+		}
+	}
+
+	if (db.opts.requireMaster == nil || *db.opts.requireMaster) && !masterHealthy {
+		return err
+	}
+	minHealthy := 0
+	if db.opts.minHealthy != nil {
+		minHealthy = *db.opts.minHealthy
+	}
+	if healthy < minHealthy || (healthy == 0 && !masterHealthy) {
+		return err
+	}
+	db.opts.logger.Printf("sqlt: opened with failed nodes: %v", err)
+	return nil
+}
@@ -0,0 +1,69 @@
+package sqlt
+
+import (
+	"context"
+	"sync"
+)
+
+type pinKey struct{}
+
+// pin is the node serving the reads of a context, per DB
+type pin struct {
+	name string
+
+	mu    sync.Mutex
+	nodes map[*DB]*node
+}
+
+// WithNode return a context which reads are all served by the named node, so a request
+// doesn't mix slaves with different lag. Empty name pin the slave picked by the first read,
+// keeping the load balanced across requests. Reads fallback to the routing while the node
+// is out of rotation
+func WithNode(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, pinKey{}, &pin{name: name, nodes: make(map[*DB]*node)})
+}
+
+func pinned(ctx context.Context) bool {
+	_, ok := ctx.Value(pinKey{}).(*pin)
+	return ok
+}
+
+// pinnedNode return the node pinned by the context, nil when the reads are not pinned
+// or the pinned node is out of rotation
+func (db *DB) pinnedNode(ctx context.Context) *node {
+	p, ok := ctx.Value(pinKey{}).(*pin)
+	if !ok {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := db.route.Load()
+	if n, ok := p.nodes[db]; ok && r.serving(n) {
+		return n
+	}
+	if p.name != "" {
+		n, err := db.node(p.name)
+		if err != nil || !r.serving(n) {
+			return nil
+		}
+		p.nodes[db] = n
+		return n
+	}
+	n := db.slave()
+	p.nodes[db] = n
+	return n
+}
+
+// serving report whether the node is master or an active slave of the routing
+func (r *routing) serving(n *node) bool {
+	if n.index == r.master {
+		return true
+	}
+	for _, slave := range r.slaves {
+		if slave == n {
+			return true
+		}
+	}
+	return false
+}
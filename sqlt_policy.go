@@ -0,0 +1,32 @@
+package sqlt
+
+import "context"
+
+type policyKey int
+
+const (
+	noRetryKey policyKey = iota
+	noHedgingKey
+)
+
+// WithoutRetry return a context which disable automatic retry for every call using it,
+// use this for statements that are not safe to repeat such as non-idempotent stored procedures
+func WithoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey, true)
+}
+
+// WithoutHedging return a context which disable hedged reads for every call using it,
+// the read is only sent to a single node even when hedging is enabled on the DB
+func WithoutHedging(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noHedgingKey, true)
+}
+
+func retryDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noRetryKey).(bool)
+	return disabled
+}
+
+func hedgingDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noHedgingKey).(bool)
+	return disabled
+}
@@ -0,0 +1,79 @@
+package sqlt
+
+import "time"
+
+// PoolConfig is the connection pool configuration of a single node, zero value keep the driver default
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// apply the config to the node, must be called with DB.mu held or before the node is shared
+func (p PoolConfig) apply(n *node) {
+	if p.MaxOpenConns > 0 {
+		n.pool.maxOpen = p.MaxOpenConns
+	}
+	if p.MaxIdleConns > 0 {
+		idle := p.MaxIdleConns
+		n.pool.maxIdle = &idle
+	}
+	if p.ConnMaxLifetime > 0 {
+		n.pool.maxLifetime = p.ConnMaxLifetime
+	}
+	if p.ConnMaxIdleTime > 0 {
+		n.pool.maxIdleTime = p.ConnMaxIdleTime
+	}
+	n.pool.apply(n.db())
+}
+
+// WithPoolConfig set the connection pool configuration of the node at open time,
+// e.g. a smaller pool for the master than the slaves
+func WithPoolConfig(name string, config PoolConfig) Option {
+	return func(o *options) {
+		o.pools[name] = config
+	}
+}
+
+// setNodePool update the pool configuration of a single node
+func (db *DB) setNodePool(name string, fn func(*poolConfig)) error {
+	n, err := db.node(name)
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	fn(&n.pool)
+	n.pool.apply(n.db())
+	return nil
+}
+
+// SetNodeMaxOpenConns set max open connections of a single node
+func (db *DB) SetNodeMaxOpenConns(name string, max int) error {
+	return db.setNodePool(name, func(p *poolConfig) {
+		p.maxOpen = max
+	})
+}
+
+// SetNodeMaxIdleConns set max idle connections of a single node
+func (db *DB) SetNodeMaxIdleConns(name string, max int) error {
+	return db.setNodePool(name, func(p *poolConfig) {
+		p.maxIdle = &max
+	})
+}
+
+// SetNodeConnMaxLifetime set the maximum amount of time a connection of a single node may be reused
+func (db *DB) SetNodeConnMaxLifetime(name string, d time.Duration) error {
+	return db.setNodePool(name, func(p *poolConfig) {
+		p.maxLifetime = d
+	})
+}
+
+// SetNodeConnMaxIdleTime set the maximum amount of time a connection of a single node may be idle
+func (db *DB) SetNodeConnMaxIdleTime(name string, d time.Duration) error {
+	return db.setNodePool(name, func(p *poolConfig) {
+		p.maxIdleTime = d
+	})
+}
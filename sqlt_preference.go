@@ -0,0 +1,126 @@
+package sqlt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ReadMode decide whether master or slaves serve the reads
+type ReadMode int
+
+const (
+	// SecondaryPreferred read from slaves, master serve the reads when no slave is active
+	SecondaryPreferred ReadMode = iota
+	// Secondary only read from slaves, reads fail with ErrAllReplicasDown when no slave is active
+	Secondary
+	// PrimaryPreferred read from master, slaves serve the reads when master is unavailable
+	PrimaryPreferred
+	// Primary only read from master
+	Primary
+)
+
+var readModeNames = map[ReadMode]string{
+	SecondaryPreferred: "secondaryPreferred",
+	Secondary:          "secondary",
+	PrimaryPreferred:   "primaryPreferred",
+	Primary:            "primary",
+}
+
+func (m ReadMode) String() string {
+	if name, ok := readModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("ReadMode(%d)", int(m))
+}
+
+type readModeKey struct{}
+
+// WithReadMode return a context which override the read mode of the DB for the reads using it
+func WithReadMode(ctx context.Context, mode ReadMode) context.Context {
+	return context.WithValue(ctx, readModeKey{}, mode)
+}
+
+// readMode return the read mode of the context, or the read mode of the DB
+func (db *DB) readMode(ctx context.Context) ReadMode {
+	if mode, ok := ctx.Value(readModeKey{}).(ReadMode); ok {
+		return mode
+	}
+	return db.opts.readPreference.Mode
+}
+
+// ReadPreference decide which slaves serve the reads, e.g. keep the reads in the same availability zone
+type ReadPreference struct {
+	// Mode decide whether master or slaves serve the reads, default to SecondaryPreferred
+	Mode ReadMode
+	// Nearest prefer the slave with the lowest latency, using the latency balancer
+	Nearest bool
+	// Tags every preferred slave must have, e.g. {"az": "us-east-1a"}
+	Tags map[string]string
+	// Fallback to any slave when no preferred slave is active, otherwise master serve the reads
+	Fallback bool
+}
+
+// WithReadPreference set the read preference
+func WithReadPreference(pref ReadPreference) Option {
+	return func(o *options) {
+		o.readPreference = pref
+		if pref.Nearest {
+			o.balancer = func(random *randSource) balancer {
+				return &latencyBalancer{decay: defaultLatencyDecay, random: random}
+			}
+		}
+	}
+}
+
+// ParseReadPreference parse comma separated preference, e.g. "nearest", "tag=az:us-east-1a, fallback=any"
+// or "primaryPreferred"
+func ParseReadPreference(s string) (ReadPreference, error) {
+	var pref ReadPreference
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		key, val, _ := strings.Cut(item, "=")
+		if mode, ok := parseReadMode(item); ok {
+			pref.Mode = mode
+			continue
+		}
+		switch {
+		case item == "":
+		case item == "nearest":
+			pref.Nearest = true
+		case item == "fallback any" || (key == "fallback" && val == "any"):
+			pref.Fallback = true
+		case key == "tag":
+			k, v, ok := strings.Cut(val, ":")
+			if !ok || k == "" {
+				return pref, fmt.Errorf("invalid tag %q, expecting key:value", val)
+			}
+			if pref.Tags == nil {
+				pref.Tags = make(map[string]string)
+			}
+			pref.Tags[k] = v
+		default:
+			return pref, fmt.Errorf("invalid read preference %q", item)
+		}
+	}
+	return pref, nil
+}
+
+func parseReadMode(s string) (ReadMode, bool) {
+	for mode, name := range readModeNames {
+		if strings.EqualFold(s, name) {
+			return mode, true
+		}
+	}
+	return 0, false
+}
+
+// matchTags report whether tags contain every wanted tag
+func matchTags(tags, want map[string]string) bool {
+	for k, v := range want {
+		if tags[k] != v {
+			return false
+		}
+	}
+	return true
+}
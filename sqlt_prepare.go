@@ -0,0 +1,215 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrStmtClosed is returned when a closed statement is used
+var ErrStmtClosed = errors.New("Statement is closed")
+
+// stmtSet hold the statement of every node, the statement is prepared lazily on first use
+// and prepared again when the node connection is reopened
+type stmtSet[S interface{ Close() error }] struct {
+	query   string
+	prepare func(ctx context.Context, db *sqlx.DB, query string) (S, error)
+	// rebind the query to the bindvar of the node, nil keep the query as is
+	rebind func(n *node, query string) string
+
+	mu     sync.Mutex
+	stmts  []preparedStmt[S]
+	closed bool
+}
+
+// preparedStmt is the statement prepared on conn
+type preparedStmt[S any] struct {
+	conn *sqlx.DB
+	stmt S
+}
+
+func newStmtSet[S interface{ Close() error }](nodes int, query string, prepare func(context.Context, *sqlx.DB, string) (S, error)) *stmtSet[S] {
+	return &stmtSet[S]{
+		query:   query,
+		prepare: prepare,
+		stmts:   make([]preparedStmt[S], nodes),
+	}
+}
+
+// get return the statement of the node, prepare it when the node has no statement yet
+func (s *stmtSet[S]) get(ctx context.Context, n *node) (S, error) {
+	var zero S
+	conn := n.db()
+
+	s.mu.Lock()
+	p, closed := s.slot(n.index), s.closed
+	s.mu.Unlock()
+	if closed {
+		return zero, ErrStmtClosed
+	}
+	if p.conn == conn {
+		return p.stmt, nil
+	}
+
+	query := s.query
+	if s.rebind != nil {
+		query = s.rebind(n, query)
+	}
+	stmt, err := s.prepare(ctx, conn, query)
+	if err != nil {
+		return zero, err
+	}
+
+	s.mu.Lock()
+	old := s.slot(n.index)
+	if s.closed || old.conn == conn {
+		// closed or prepared by another caller in the meantime
+		s.mu.Unlock()
+		stmt.Close()
+		if s.closed {
+			return zero, ErrStmtClosed
+		}
+		return old.stmt, nil
+	}
+	s.stmts[n.index] = preparedStmt[S]{conn: conn, stmt: stmt}
+	s.mu.Unlock()
+
+	// the old statement belong to a reopened connection
+	if old.conn != nil {
+		old.stmt.Close()
+	}
+	return stmt, nil
+}
+
+// stream prepare the statement on the connection reserved by the streamed call, so the rows hold
+// the slot and the contexts of the call until they are closed
+func (s *stmtSet[S]) stream(ctx context.Context, db *DB, n *node) (*sqlx.Stmt, error) {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return nil, ErrStmtClosed
+	}
+	query := s.query
+	if s.rebind != nil {
+		query = s.rebind(n, query)
+	}
+	return db.prepare(ctx, n, query)
+}
+
+// slot return the statement at node index i, growing the list for nodes added after the prepare.
+// Must be called with s.mu held
+func (s *stmtSet[S]) slot(i int) preparedStmt[S] {
+	for len(s.stmts) <= i {
+		s.stmts = append(s.stmts, preparedStmt[S]{})
+	}
+	return s.stmts[i]
+}
+
+// warm prepare the statement on the given nodes, error is only returned when every node fail
+// so a node being down doesn't fail the whole prepare, it is prepared when it is used
+func (s *stmtSet[S]) warm(ctx context.Context, nodes []*node) error {
+	var firstErr error
+	for _, n := range nodes {
+		_, err := s.get(ctx, n)
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// close every prepared statement
+func (s *stmtSet[S]) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+
+	var firstErr error
+	for i, p := range s.stmts {
+		if p.conn == nil {
+			continue
+		}
+		if err := p.stmt.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		s.stmts[i] = preparedStmt[S]{}
+	}
+	return firstErr
+}
+
+func prepareStmt(ctx context.Context, db *sqlx.DB, query string) (*sql.Stmt, error) {
+	return db.PrepareContext(ctx, query)
+}
+
+func preparexStmt(ctx context.Context, db *sqlx.DB, query string) (*sqlx.Stmt, error) {
+	return db.PreparexContext(ctx, query)
+}
+
+// Prepare return sql stmt
+func (db *DB) Prepare(query string) (*Stmt, error) {
+	return db.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext return sql stmt, the statement is prepared on every node lazily on first use
+func (db *DB) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
+	st := &Stmt{db: db, stmts: newStmtSet(len(db.nodeList()), query, prepareStmt)}
+	st.stmts.rebind = db.rebind
+	if err := st.stmts.warm(ctx, db.members()); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Preparex sqlx stmt
+func (db *DB) Preparex(query string) (*Stmtx, error) {
+	return db.PreparexContext(context.Background(), query)
+}
+
+// PreparexContext return sqlx stmt, the statement is prepared on every node lazily on first use
+func (db *DB) PreparexContext(ctx context.Context, query string) (*Stmtx, error) {
+	st := &Stmtx{db: db, stmts: newStmtSet(len(db.nodeList()), query, preparexStmt)}
+	st.stmts.rebind = db.rebind
+	if err := st.stmts.warm(ctx, db.members()); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// PrepareMaster return sql stmt prepared only on master, every call of the statement
+// use master. Use this for write statements to avoid preparing them on slaves
+func (db *DB) PrepareMaster(query string) (*Stmt, error) {
+	return db.PrepareMasterContext(context.Background(), query)
+}
+
+// PrepareMasterContext return sql stmt prepared only on master
+func (db *DB) PrepareMasterContext(ctx context.Context, query string) (*Stmt, error) {
+	st := &Stmt{db: db, stmts: newStmtSet(len(db.nodeList()), query, prepareStmt), masterOnly: true}
+	st.stmts.rebind = db.rebind
+	if err := st.stmts.warm(ctx, []*node{db.nodeAt(db.master())}); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// PreparexMaster return sqlx stmt prepared only on master, every call of the statement
+// use master. Use this for write statements to avoid preparing them on slaves
+func (db *DB) PreparexMaster(query string) (*Stmtx, error) {
+	return db.PreparexMasterContext(context.Background(), query)
+}
+
+// PreparexMasterContext return sqlx stmt prepared only on master
+func (db *DB) PreparexMasterContext(ctx context.Context, query string) (*Stmtx, error) {
+	st := &Stmtx{db: db, stmts: newStmtSet(len(db.nodeList()), query, preparexStmt), masterOnly: true}
+	st.stmts.rebind = db.rebind
+	if err := st.stmts.warm(ctx, []*node{db.nodeAt(db.master())}); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
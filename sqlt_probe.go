@@ -0,0 +1,60 @@
+package sqlt
+
+import (
+	"context"
+	"time"
+)
+
+// defaultMaxProbeBackoff cap the backoff of the probes to a down node
+const defaultMaxProbeBackoff = time.Second * 30
+
+// maxProbeBackoff return the cap of the backoff of the probes to a down node
+func (db *DB) maxProbeBackoff() time.Duration {
+	if db.opts.thresholds.MaxBackoff > 0 {
+		return db.opts.thresholds.MaxBackoff
+	}
+	return defaultMaxProbeBackoff
+}
+
+// scheduleProbe set the next probe of the down node after its backoff, must be called with DB.mu held
+func (n *node) scheduleProbe(now time.Time) {
+	n.nextProbe.Store(now.Add(n.probeInterval()).UnixNano())
+}
+
+// downNodes return the down nodes which next probe is due at now, the probe of the returned nodes
+// is pushed back so a slow probe is not started twice
+func (db *DB) downNodes(now time.Time) []*node {
+	var due []*node
+	for _, n := range db.members() {
+		next := n.nextProbe.Load()
+		if next == 0 || now.UnixNano() < next {
+			continue
+		}
+		if n.nextProbe.CompareAndSwap(next, now.Add(n.probeInterval()).UnixNano()) {
+			due = append(due, n)
+		}
+	}
+	return due
+}
+
+// probe the down nodes on every tick until ctx is done, separate from the heartbeat
+// so unreachable hosts don't delay the ping of healthy nodes
+func (db *DB) probe(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			db.probeDue(ctx, now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// probeDue ping the down nodes due at now
+func (db *DB) probeDue(ctx context.Context, now time.Time) {
+	if due := db.downNodes(now); len(due) > 0 {
+		db.ping(ctx, due)
+	}
+}
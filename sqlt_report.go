@@ -0,0 +1,142 @@
+package sqlt
+
+import (
+	"sync"
+	"time"
+)
+
+// RoutingReport summarize how queries were routed over a window
+type RoutingReport struct {
+	Since  time.Time     `json:"since"`
+	Window time.Duration `json:"window"`
+	// Reads is the number of reads served per node
+	Reads map[string]uint64 `json:"reads"`
+	// Share is the fraction of reads served per node
+	Share  map[string]float64 `json:"share"`
+	Writes uint64             `json:"writes"`
+	// Skipped is the number of reads a node was left out of the rotation because it was inactive
+	Skipped map[string]uint64 `json:"skipped"`
+	// Fallbacks is the number of reads served by master because no slave was active
+	Fallbacks uint64 `json:"fallbacks"`
+	Retries   uint64 `json:"retries"`
+	// Hedges is the number of reads sent to a second slave
+	Hedges uint64 `json:"hedges"`
+}
+
+const (
+	reportBucketWidth = time.Second * 10
+	reportBuckets     = 360
+	// MaxReportWindow is the longest window kept for RoutingReport
+	MaxReportWindow = reportBucketWidth * reportBuckets
+)
+
+type reportBucket struct {
+	start     time.Time
+	reads     map[string]uint64
+	skipped   map[string]uint64
+	writes    uint64
+	fallbacks uint64
+	retries   uint64
+	hedges    uint64
+}
+
+// routingRecorder keep routing counters in fixed width time buckets
+type routingRecorder struct {
+	mu      sync.Mutex
+	buckets [reportBuckets]reportBucket
+}
+
+func newRoutingRecorder() *routingRecorder {
+	return &routingRecorder{}
+}
+
+// bucket return the bucket of current time, must be called with mu held
+func (rr *routingRecorder) bucket() *reportBucket {
+	start := time.Now().Truncate(reportBucketWidth)
+	b := &rr.buckets[(start.UnixNano()/int64(reportBucketWidth))%reportBuckets]
+	if !b.start.Equal(start) {
+		*b = reportBucket{
+			start:   start,
+			reads:   make(map[string]uint64),
+			skipped: make(map[string]uint64),
+		}
+	}
+	return b
+}
+
+func (rr *routingRecorder) record(c call, n *node, r *routing) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	b := rr.bucket()
+	if c.write {
+		b.writes++
+		return
+	}
+
+	b.reads[n.name]++
+	if c.master {
+		return
+	}
+	if r.total == 0 {
+		b.fallbacks++
+	}
+	for _, name := range r.skipped {
+		b.skipped[name]++
+	}
+}
+
+func (rr *routingRecorder) retry() {
+	rr.mu.Lock()
+	rr.bucket().retries++
+	rr.mu.Unlock()
+}
+
+func (rr *routingRecorder) hedge() {
+	rr.mu.Lock()
+	rr.bucket().hedges++
+	rr.mu.Unlock()
+}
+
+// RoutingReport summarize how queries were routed in the last window, up to MaxReportWindow
+func (db *DB) RoutingReport(window time.Duration) RoutingReport {
+	if window > MaxReportWindow {
+		window = MaxReportWindow
+	}
+
+	now := time.Now()
+	report := RoutingReport{
+		Since:   now.Add(-window),
+		Window:  window,
+		Reads:   make(map[string]uint64),
+		Share:   make(map[string]float64),
+		Skipped: make(map[string]uint64),
+	}
+
+	db.report.mu.Lock()
+	for _, b := range db.report.buckets {
+		if b.start.IsZero() || b.start.Add(reportBucketWidth).Before(report.Since) || b.start.After(now) {
+			continue
+		}
+		for name, count := range b.reads {
+			report.Reads[name] += count
+		}
+		for name, count := range b.skipped {
+			report.Skipped[name] += count
+		}
+		report.Writes += b.writes
+		report.Fallbacks += b.fallbacks
+		report.Retries += b.retries
+		report.Hedges += b.hedges
+	}
+	db.report.mu.Unlock()
+
+	var total uint64
+	for _, count := range report.Reads {
+		total += count
+	}
+	for name, count := range report.Reads {
+		report.Share[name] = float64(count) / float64(total)
+	}
+	return report
+}
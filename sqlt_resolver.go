@@ -0,0 +1,201 @@
+package sqlt
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Resolver resolve the hostname of a node into addresses, *net.Resolver satisfy this interface
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// WithResolver resolve the hosts of every DSN using r when the node is opened and every time
+// an inactive node is reconnected, the node is reopened when the addresses changed. Resolution is
+// cached for ttl, zero ttl disable the cache. The DSN given to the driver keep the hostname so TLS
+// still verify it, register DialContext with the driver to connect to the resolved addresses
+func WithResolver(r Resolver, ttl time.Duration) Option {
+	return func(o *options) {
+		if ttl > 0 {
+			r = CachedResolver(r, ttl)
+		}
+		o.resolver = r
+	}
+}
+
+// CachedResolver return a resolver caching the results of r for ttl, share it between
+// WithResolver and DialContext so both see the same addresses
+func CachedResolver(r Resolver, ttl time.Duration) Resolver {
+	if c, ok := r.(*cachedResolver); ok && c.ttl == ttl {
+		return c
+	}
+	return &cachedResolver{resolver: r, ttl: ttl, entries: make(map[string]resolution)}
+}
+
+// DialContext return a dial function connecting to the first reachable address of the host resolved
+// by r, for drivers accepting a dialer, e.g. the DialFunc of pgx or mysql.RegisterDialContext.
+// pgx can also use r.LookupHost as its LookupFunc
+func DialContext(r Resolver) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		addrs, err := r.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return nil, &net.DNSError{Err: "no address found", Name: host, IsNotFound: true}
+		}
+		for _, ip := range addrs {
+			var conn net.Conn
+			if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+type resolution struct {
+	addrs   []string
+	expired time.Time
+}
+
+// cachedResolver cache the resolution result until the ttl expired
+type cachedResolver struct {
+	resolver Resolver
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]resolution
+}
+
+func (c *cachedResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expired) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := c.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[host] = resolution{addrs: addrs, expired: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return addrs, nil
+}
+
+// resolveAddress resolve every host of the DSN, the result only change when the addresses
+// of a host changed. Empty without resolver, unix sockets and IP hosts are kept as is
+func (db *DB) resolveAddress(ctx context.Context, dsn string) (string, error) {
+	if db.opts.resolver == nil {
+		return "", nil
+	}
+
+	var resolved []string
+	for _, host := range dsnHosts(dsn) {
+		if host == "" || strings.HasPrefix(host, "/") || net.ParseIP(host) != nil {
+			resolved = append(resolved, host)
+			continue
+		}
+		addrs, err := db.opts.resolver.LookupHost(ctx, host)
+		if err != nil || len(addrs) == 0 {
+			return "", &net.DNSError{Err: "no address found", Name: host, IsNotFound: err == nil}
+		}
+		addrs = slices.Clone(addrs)
+		slices.Sort(addrs)
+		resolved = append(resolved, strings.Join(addrs, ","))
+	}
+	return strings.Join(resolved, ";"), nil
+}
+
+// reresolve resolve the node hosts again and reopen the node when their addresses changed,
+// the old pool, connected to the old addresses, is closed in the background
+func (db *DB) reresolve(ctx context.Context, n *node) error {
+	if db.opts.resolver == nil {
+		return nil
+	}
+
+	n.reopenMu.Lock()
+	defer n.reopenMu.Unlock()
+
+	addr, err := db.resolveAddress(ctx, n.dsn)
+	if err != nil {
+		return err
+	}
+	if addr == n.address {
+		return nil
+	}
+
+	conn, err := db.openNode(n.name, n.driver, n.dsn)
+	if err != nil {
+		return err
+	}
+	old := db.swapConn(n, conn)
+	n.address = addr
+	db.opts.logger.Printf("sqlt: node %s reopened on %s", n.name, addr)
+	// the node is healthy on the new pool whatever the old one return, its queries are left to finish
+	go func() {
+		if err := old.Close(); err != nil {
+			db.opts.logger.Printf("sqlt: closing the old pool of node %s: %v", n.name, err)
+		}
+	}()
+	return nil
+}
+
+// ReopenNode replace the DSN of the node, e.g. after the replica host was replaced or its password
+// rotated. The new pool is pinged and swapped in, cached statements are prepared again on it
+// and the old pool is closed once its queries are done. Other nodes are not touched
+func (db *DB) ReopenNode(ctx context.Context, name, dsn string) error {
+	n, err := db.node(name)
+	if err != nil {
+		return err
+	}
+
+	n.reopenMu.Lock()
+	defer n.reopenMu.Unlock()
+
+	addr, err := db.resolveAddress(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	conn, err := db.openNode(n.name, n.driver, dsn)
+	if err != nil {
+		return err
+	}
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return wrapError(err, n, "ReopenNode", "")
+	}
+
+	old := db.swapConn(n, conn)
+	n.setDSN(dsn)
+	n.address = addr
+	if db.stmtCache != nil {
+		db.stmtCache.prepare(ctx, n)
+	}
+	db.opts.logger.Printf("sqlt: node %s reopened", n.name)
+	// Close wait for the queries running on the old pool
+	return old.Close()
+}
+
+// swapConn apply the pool config of the node to conn and swap it in, the old conn is returned
+func (db *DB) swapConn(n *node, conn *sqlx.DB) *sqlx.DB {
+	db.mu.Lock()
+	n.pool.apply(conn)
+	db.mu.Unlock()
+	return n.conn.Swap(conn)
+}
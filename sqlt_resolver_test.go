@@ -0,0 +1,58 @@
+package sqlt
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReresolve(t *testing.T) {
+	resolver := &staticResolver{}
+	db := newMockDB(t, 1, WithResolver(resolver, 0))
+	n, err := db.node("slave-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.setDSN("host=replica.local port=5432")
+
+	tests := []struct {
+		name     string
+		resolved []string
+		reopened bool
+		wantErr  bool
+	}{
+		{name: "addresses changed", resolved: []string{"10.0.0.1"}, reopened: true},
+		{name: "addresses unchanged", resolved: []string{"10.0.0.1"}},
+		{name: "address added", resolved: []string{"10.0.0.2", "10.0.0.1"}, reopened: true},
+		{name: "same addresses in another order", resolved: []string{"10.0.0.1", "10.0.0.2"}},
+		{name: "host not resolved", resolved: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver.set(tt.resolved...)
+			old := n.db()
+			err := db.reresolve(context.Background(), n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if reopened := n.db() != old; reopened != tt.reopened {
+				t.Fatalf("reopened %v, want %v", reopened, tt.reopened)
+			}
+			if !tt.reopened {
+				return
+			}
+
+			// the old pool is closed in the background and the new one serve the node
+			if err := n.db().PingContext(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			deadline := time.Now().Add(time.Second)
+			for old.PingContext(context.Background()) == nil {
+				if time.Now().After(deadline) {
+					t.Fatal("old pool not closed")
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Tx is a master transaction with the query helpers, logging and metrics of DB,
+// supporting nested transactions through savepoints
+type Tx struct {
+	*sqlx.Tx
+	db  *DB
+	n   *node
+	ctx context.Context
+	// writes are notified to the cache hooks on commit
+	writes []txWrite
+	// savepoints is the number of savepoints created, used to name the next one
+	savepoints int
+	// cancels release the contexts of the streamed queries, their rows are closed with the transaction
+	cancels []context.CancelFunc
+}
+
+// Transact run fn inside a master transaction like InTx, fn can nest transactions with Tx.Nested
+func (db *DB) Transact(ctx context.Context, opts *sql.TxOptions, fn func(tx *Tx) error) error {
+	if !db.enter() {
+		return ErrShutdown
+	}
+	defer db.leave()
+
+	return db.retry(ctx, *db.txRetry.Load(), func() error {
+		return db.inTx(ctx, opts, fn)
+	})
+}
+
+// Nested run fn inside a savepoint, only the work of fn is rolled back when it return error
+// or panic, the error is returned to be handled or propagated by the caller
+func (tx *Tx) Nested(ctx context.Context, fn func(tx *Tx) error) (err error) {
+	tx.savepoints++
+	name := fmt.Sprintf("sqlt_savepoint_%d", tx.savepoints)
+	sp := savepointSyntax(tx.n.dialect)
+	if _, err := tx.Tx.ExecContext(ctx, sp.create+name); err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Tx.ExecContext(ctx, sp.rollback+name)
+			panic(p)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		if _, rbErr := tx.Tx.ExecContext(ctx, sp.rollback+name); rbErr != nil {
+			return fmt.Errorf("%w, rollback to savepoint: %v", err, rbErr)
+		}
+		return err
+	}
+	if sp.release == "" {
+		return nil
+	}
+	_, err = tx.Tx.ExecContext(ctx, sp.release+name)
+	return err
+}
+
+// savepoint is the statement prefixes managing a savepoint, empty release when the savepoint
+// is released with the transaction
+type savepoint struct {
+	create   string
+	rollback string
+	release  string
+}
+
+// savepointSyntax return the savepoint statements of the dialect
+func savepointSyntax(d Dialect) savepoint {
+	if d == DialectSQLServer {
+		return savepoint{create: "SAVE TRANSACTION ", rollback: "ROLLBACK TRANSACTION "}
+	}
+	return savepoint{create: "SAVEPOINT ", rollback: "ROLLBACK TO SAVEPOINT ", release: "RELEASE SAVEPOINT "}
+}
@@ -0,0 +1,159 @@
+package sqlt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// defaultShadowTimeout and defaultShadowInflight bound the mirrored queries so a slow shadow
+// group can't pile up goroutines
+const (
+	defaultShadowTimeout  = time.Second * 5
+	defaultShadowInflight = 100
+)
+
+// Shadow mirror a share of the traffic to another group, e.g. the cluster being migrated to.
+// The mirrored queries run in the background and never change the result of the call
+type Shadow struct {
+	DB *DB
+	// ReadRate is the share of reads mirrored, between 0 and 1
+	ReadRate float64
+	// WriteRate is the share of writes mirrored, zero by default since mirrored writes change the shadow data
+	WriteRate float64
+	// Compare is called with both results once the mirrored query is done
+	Compare func(r ShadowResult)
+	// Timeout of the mirrored query, default to 5 seconds
+	Timeout time.Duration
+	// MaxInflight is the maximum number of mirrored queries running, the calls above it are not
+	// mirrored. Default to 100
+	MaxInflight int
+}
+
+// ShadowResult compare a call with its mirror on the shadow group
+type ShadowResult struct {
+	Op    string `json:"op"`
+	Query string `json:"query"`
+	// Primary and Shadow are the rows read encoded in JSON, nil for writes
+	Primary        json.RawMessage `json:"primary,omitempty"`
+	Shadow         json.RawMessage `json:"shadow,omitempty"`
+	PrimaryErr     error           `json:"-"`
+	ShadowErr      error           `json:"-"`
+	PrimaryLatency time.Duration   `json:"primary_latency_ns"`
+	ShadowLatency  time.Duration   `json:"shadow_latency_ns"`
+	// Match is set when both calls failed or both returned the same rows
+	Match bool `json:"match"`
+}
+
+// WithShadow mirror Get, Select and their named variants, and Exec when WriteRate is set, to the
+// shadow group. Streamed rows, transactions and calls targeting a node are not mirrored
+func WithShadow(s Shadow) Option {
+	return func(o *options) {
+		if s.DB == nil {
+			return
+		}
+		if s.Timeout <= 0 {
+			s.Timeout = defaultShadowTimeout
+		}
+		if s.MaxInflight <= 0 {
+			s.MaxInflight = defaultShadowInflight
+		}
+		o.shadow = &s
+		o.pipeline = true
+	}
+}
+
+// shadowCall return the function running the call on the shadow group, nil when the op is not mirrored.
+// dest is a new value of the call dest type
+func shadowCall(op string) func(ctx context.Context, shadow *DB, c call, dest interface{}) error {
+	switch op {
+	case "Get":
+		return func(ctx context.Context, shadow *DB, c call, dest interface{}) error {
+			return shadow.GetContext(ctx, dest, c.query, c.args...)
+		}
+	case "GetMaster":
+		return func(ctx context.Context, shadow *DB, c call, dest interface{}) error {
+			return shadow.GetMasterContext(ctx, dest, c.query, c.args...)
+		}
+	case "Select":
+		return func(ctx context.Context, shadow *DB, c call, dest interface{}) error {
+			return shadow.SelectContext(ctx, dest, c.query, c.args...)
+		}
+	case "SelectMaster":
+		return func(ctx context.Context, shadow *DB, c call, dest interface{}) error {
+			return shadow.SelectMasterContext(ctx, dest, c.query, c.args...)
+		}
+	case "NamedGet":
+		return func(ctx context.Context, shadow *DB, c call, dest interface{}) error {
+			return shadow.NamedGetContext(ctx, dest, c.query, c.args[0])
+		}
+	case "NamedSelect":
+		return func(ctx context.Context, shadow *DB, c call, dest interface{}) error {
+			return shadow.NamedSelectContext(ctx, dest, c.query, c.args[0])
+		}
+	case "Exec":
+		return func(ctx context.Context, shadow *DB, c call, _ interface{}) error {
+			_, err := shadow.ExecContext(ctx, c.query, c.args...)
+			return err
+		}
+	case "NamedExec":
+		return func(ctx context.Context, shadow *DB, c call, _ interface{}) error {
+			_, err := shadow.NamedExecContext(ctx, c.query, c.args[0])
+			return err
+		}
+	}
+	return nil
+}
+
+// mirror run a sample of the calls on the shadow group in the background, the rows read are
+// encoded before returning since the caller own dest afterward
+func (db *DB) mirror(ctx context.Context, c call, elapsed time.Duration, err error) {
+	s := db.opts.shadow
+	if s == nil || c.stream || c.target != nil {
+		return
+	}
+	run := shadowCall(c.op)
+	if run == nil {
+		return
+	}
+	rate := s.ReadRate
+	if c.write {
+		rate = s.WriteRate
+	}
+	if rate <= 0 || db.random.Float64() >= rate {
+		return
+	}
+	if db.shadowing.Add(1) > int64(s.MaxInflight) {
+		db.shadowing.Add(-1)
+		return
+	}
+
+	var dest interface{}
+	result := ShadowResult{Op: c.op, Query: c.query, PrimaryErr: err, PrimaryLatency: elapsed}
+	if !c.write && c.dest != nil && reflect.TypeOf(c.dest).Kind() == reflect.Ptr {
+		dest = reflect.New(reflect.TypeOf(c.dest).Elem()).Interface()
+		if err == nil {
+			result.Primary, _ = json.Marshal(c.dest)
+		}
+	}
+
+	// the mirror outlive the call, it keep the context values but not its cancellation
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.Timeout)
+	go func() {
+		defer db.shadowing.Add(-1)
+		defer cancel()
+
+		start := time.Now()
+		result.ShadowErr = run(ctx, s.DB, c, dest)
+		result.ShadowLatency = time.Since(start)
+		if result.ShadowErr == nil && dest != nil {
+			result.Shadow, _ = json.Marshal(dest)
+		}
+		result.Match = (result.PrimaryErr == nil) == (result.ShadowErr == nil) && bytes.Equal(result.Primary, result.Shadow)
+		if s.Compare != nil {
+			s.Compare(result)
+		}
+	}()
+}
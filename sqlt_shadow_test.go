@@ -0,0 +1,94 @@
+package sqlt
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestShadow(t *testing.T) {
+	tests := []struct {
+		name   string
+		shadow Shadow
+		// dsns of the shadow group, the reads of the shadow match when its slave is named like the primary slave
+		dsns []string
+		call func(db *DB) error
+		// mirrored is set when the call is mirrored, match when the results of both groups are the same
+		mirrored bool
+		match    bool
+	}{
+		{
+			name:     "read mirrored",
+			shadow:   Shadow{ReadRate: 1},
+			dsns:     []string{"master", "slave-1"},
+			call:     func(db *DB) error { var v string; return db.Get(&v, "SELECT dsn") },
+			mirrored: true,
+			match:    true,
+		},
+		{
+			name:     "read mismatch",
+			shadow:   Shadow{ReadRate: 1},
+			dsns:     []string{"shadow-master", "shadow-slave-1"},
+			call:     func(db *DB) error { var v []string; return db.Select(&v, "SELECT dsn") },
+			mirrored: true,
+			match:    false,
+		},
+		{
+			name:   "read not sampled",
+			shadow: Shadow{ReadRate: 0},
+			dsns:   []string{"master", "slave-1"},
+			call:   func(db *DB) error { var v string; return db.Get(&v, "SELECT dsn") },
+		},
+		{
+			name:   "write not mirrored by default",
+			shadow: Shadow{ReadRate: 1},
+			dsns:   []string{"master", "slave-1"},
+			call:   func(db *DB) error { _, err := db.Exec("UPDATE stock SET quantity = 0"); return err },
+		},
+		{
+			name:     "write mirrored",
+			shadow:   Shadow{WriteRate: 1},
+			dsns:     []string{"master", "slave-1"},
+			call:     func(db *DB) error { _, err := db.Exec("UPDATE stock SET quantity = 0"); return err },
+			mirrored: true,
+			match:    true,
+		},
+		{
+			name:   "streamed rows not mirrored",
+			shadow: Shadow{ReadRate: 1},
+			dsns:   []string{"master", "slave-1"},
+			call: func(db *DB) error {
+				rows, err := db.QueryContext(context.Background(), "SELECT dsn")
+				if err == nil {
+					rows.Close()
+				}
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := make(chan ShadowResult, 1)
+			tt.shadow.DB = newMockDBWithDSN(t, tt.dsns)
+			tt.shadow.Compare = func(r ShadowResult) { results <- r }
+			db := newMockDB(t, 1, WithShadow(tt.shadow))
+
+			if err := tt.call(db); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case r := <-results:
+				if !tt.mirrored {
+					t.Fatalf("call mirrored: %+v", r)
+				}
+				if r.Match != tt.match {
+					t.Fatalf("match %v, want %v: primary %s, shadow %s", r.Match, tt.match, r.Primary, r.Shadow)
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.mirrored {
+					t.Fatal("call not mirrored")
+				}
+			}
+		})
+	}
+}
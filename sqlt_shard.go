@@ -0,0 +1,73 @@
+package sqlt
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// ShardFunc return the index of the shard serving key among shards
+type ShardFunc func(key string, shards int) int
+
+// HashShard spread the keys evenly across the shards by their FNV-1a hash
+func HashShard(key string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// RangeShard route keys lower than bounds[i] to shard i and the remaining keys to the last shard,
+// keys are compared as strings so numeric keys must be zero padded
+func RangeShard(bounds ...string) ShardFunc {
+	sorted := append([]string(nil), bounds...)
+	sort.Strings(sorted)
+	return func(key string, shards int) int {
+		i := sort.Search(len(sorted), func(i int) bool { return key < sorted[i] })
+		return min(i, shards-1)
+	}
+}
+
+// ShardedDB route queries by shard key across connection groups, every shard is a master and
+// slaves group. The embedded Manager provide the heartbeat, aggregated status and close of the shards
+type ShardedDB struct {
+	*Manager
+	shards []*DB
+	shard  ShardFunc
+}
+
+// NewShardedDB return the sharded DB of shards in order, the shards are registered in the manager
+// by group name, or "shard-<index>" when the group has no name
+func NewShardedDB(shard ShardFunc, shards ...*DB) (*ShardedDB, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("No shards found")
+	}
+	if shard == nil {
+		shard = HashShard
+	}
+	m := NewManager()
+	for i, db := range shards {
+		name := db.GroupName()
+		if name == defaultGroupName {
+			name = fmt.Sprintf("shard-%d", i)
+		}
+		if err := m.Add(name, db); err != nil {
+			return nil, err
+		}
+	}
+	return &ShardedDB{Manager: m, shards: shards, shard: shard}, nil
+}
+
+// Shard return the group serving key, an out of range shard index fallback to the first shard
+func (s *ShardedDB) Shard(key string) *DB {
+	i := s.shard(key, len(s.shards))
+	if i < 0 || i >= len(s.shards) {
+		i = 0
+	}
+	return s.shards[i]
+}
+
+// Shards return every shard in order, e.g. to fan out a query
+func (s *ShardedDB) Shards() []*DB {
+	return append([]*DB(nil), s.shards...)
+}
@@ -0,0 +1,41 @@
+package sqlt
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Shutdown gracefully close the database. The heartbeat is stopped, new queries fail with ErrShutdown,
+// then routed queries and transactions are waited until they finish or ctx expire before every node
+// is closed. Rows returned before the shutdown must be closed by the caller
+func (db *DB) Shutdown(ctx context.Context) error {
+	db.StopBeat()
+	db.shutdown.Store(true)
+
+	ticker := time.NewTicker(time.Millisecond * 10)
+	defer ticker.Stop()
+	for db.inflight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			// close anyway, the remaining queries fail with closed connection
+			return errors.Join(ctx.Err(), db.Close())
+		case <-ticker.C:
+		}
+	}
+	return db.Close()
+}
+
+// enter register a routed call, return false when the database is shutting down
+func (db *DB) enter() bool {
+	db.inflight.Add(1)
+	if db.shutdown.Load() {
+		db.inflight.Add(-1)
+		return false
+	}
+	return true
+}
+
+func (db *DB) leave() {
+	db.inflight.Add(-1)
+}
@@ -0,0 +1,50 @@
+package sqlt
+
+import (
+	"context"
+	"time"
+)
+
+// singleNode return master when the call can take the fast path of a single node group, nil otherwise
+func (db *DB) singleNode(ctx context.Context, c call) *node {
+	if !db.lean {
+		return nil
+	}
+	r := db.route.Load()
+	if !r.single || (c.target != nil && c.target.index != r.master) {
+		return nil
+	}
+	// Secondary mode fail the reads of a group without slaves
+	if !c.write && !c.master && db.readMode(ctx) == Secondary {
+		return nil
+	}
+	return db.nodeAt(r.master)
+}
+
+// runSingle execute fn against the only node of the group, skipping the node selection
+// and the balancer. Query counters, latency window and routing report are still kept
+func (db *DB) runSingle(ctx context.Context, c call, n *node, fn func(ctx context.Context, n *node, query string) error) error {
+	n.inflight.Add(1)
+	db.report.record(c, n, db.route.Load())
+	ctx, cancel := db.queryContext(ctx, n)
+	fnCtx, s := db.stream(ctx, c)
+	defer s.close(func() {
+		cancel()
+		n.inflight.Add(-1)
+	})
+
+	start := time.Now()
+	err := n.injected(ctx)
+	if err == nil {
+		err = fn(fnCtx, n, db.rebind(n, c.query))
+	}
+	err = n.track(err)
+	elapsed := time.Since(start)
+	n.window.record(elapsed)
+	if err == nil && c.write {
+		db.invalidateCache(ctx, c)
+		db.capturePosition(ctx, n)
+	}
+	recordMetadata(ctx, c, n, elapsed, err)
+	return wrapError(err, n, c.op, c.query)
+}
@@ -0,0 +1,112 @@
+package sqlt
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// source is a single parsed connection source, a source can be labeled with
+// a name and attributes, e.g. "slave-big=user:pass@tcp(host:3306)/db,weight=3,tag=az:us-east-1a".
+// The driver attribute override the driver of the group for the node, e.g. ",driver=pgx"
+type source struct {
+	name   string
+	dsn    string
+	weight int
+	label  string
+	tags   map[string]string
+	driver string
+}
+
+var (
+	sourceLabelRegexp = regexp.MustCompile(`^([A-Za-z][\w-]*)=`)
+	sourceAttrRegexp  = regexp.MustCompile(`,(weight|label|tag|driver)=([^,=]*)$`)
+)
+
+// libpq keywords is never considered as source label, so key/value DSN keep working.
+// The list hold every libpq connection parameter and the extra parameters of lib/pq and pgx
+var libpqKeywords = map[string]bool{
+	// libpq
+	"host": true, "hostaddr": true, "port": true, "dbname": true, "user": true,
+	"password": true, "passfile": true, "require_auth": true, "channel_binding": true,
+	"connect_timeout": true, "client_encoding": true, "options": true,
+	"application_name": true, "fallback_application_name": true,
+	"keepalives": true, "keepalives_idle": true, "keepalives_interval": true, "keepalives_count": true,
+	"tcp_user_timeout": true, "replication": true, "gssencmode": true,
+	"sslmode": true, "requiressl": true, "sslnegotiation": true, "sslcompression": true,
+	"sslcert": true, "sslkey": true, "sslkeylogfile": true, "sslpassword": true, "sslcertmode": true,
+	"sslrootcert": true, "sslcrl": true, "sslcrldir": true, "sslsni": true, "requirepeer": true,
+	"ssl_min_protocol_version": true, "ssl_max_protocol_version": true,
+	"min_protocol_version": true, "max_protocol_version": true,
+	"krbsrvname": true, "gsslib": true, "gssdelegation": true, "service": true,
+	"target_session_attrs": true, "load_balance_hosts": true,
+	"oauth_issuer": true, "oauth_client_id": true, "oauth_client_secret": true, "oauth_scope": true,
+	// lib/pq
+	"binary_parameters": true, "krbspn": true, "disable_prepared_binary_result": true,
+	// pgx
+	"default_query_exec_mode": true, "statement_cache_capacity": true, "description_cache_capacity": true,
+	"pool_max_conns": true, "pool_min_conns": true, "pool_min_idle_conns": true,
+	"pool_max_conn_lifetime": true, "pool_max_conn_lifetime_jitter": true,
+	"pool_max_conn_idle_time": true, "pool_health_check_period": true,
+}
+
+// parseSource parse the connection source, the name is empty when the source is not labeled
+func parseSource(src string) (source, error) {
+	s := source{dsn: strings.TrimSpace(src), weight: 1}
+
+	for {
+		match := sourceAttrRegexp.FindStringSubmatchIndex(s.dsn)
+		if match == nil {
+			break
+		}
+		key, val := s.dsn[match[2]:match[3]], s.dsn[match[4]:match[5]]
+		switch key {
+		case "weight":
+			weight, err := strconv.Atoi(val)
+			if err != nil || weight < 1 {
+				return s, fmt.Errorf("invalid weight %q", val)
+			}
+			s.weight = weight
+		case "label":
+			s.label = val
+		case "driver":
+			s.driver = val
+		case "tag":
+			key, value, ok := strings.Cut(val, ":")
+			if !ok || key == "" {
+				return s, fmt.Errorf("invalid tag %q, expecting key:value", val)
+			}
+			if s.tags == nil {
+				s.tags = make(map[string]string)
+			}
+			s.tags[key] = value
+		}
+		s.dsn = s.dsn[:match[0]]
+	}
+
+	if match := sourceLabelRegexp.FindStringSubmatch(s.dsn); match != nil && labeled(match[1], s.dsn[len(match[0]):]) {
+		s.name = match[1]
+		s.dsn = s.dsn[len(match[0]):]
+	}
+	return s, nil
+}
+
+// labeled report whether key is the label of the DSN following it. A key/value DSN starting with
+// a parameter missing from libpqKeywords, e.g. "search_path=app host=db", is not labeled: the value
+// of its first key is followed by key/value pairs while a labeled key/value DSN start with one
+func labeled(key, dsn string) bool {
+	if libpqKeywords[key] {
+		return false
+	}
+	fields := strings.Fields(dsn)
+	if len(fields) < 2 || strings.Contains(fields[0], "=") {
+		return true
+	}
+	for _, field := range fields[1:] {
+		if strings.Contains(field, "=") {
+			return false
+		}
+	}
+	return true
+}
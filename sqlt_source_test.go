@@ -0,0 +1,32 @@
+package sqlt
+
+import "testing"
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		src      string
+		wantName string
+		wantDSN  string
+	}{
+		{src: "user:pass@tcp(db:3306)/app", wantDSN: "user:pass@tcp(db:3306)/app"},
+		{src: "slave-big=user:pass@tcp(db:3306)/app", wantName: "slave-big", wantDSN: "user:pass@tcp(db:3306)/app"},
+		{src: "replica=postgres://db/app?sslmode=disable", wantName: "replica", wantDSN: "postgres://db/app?sslmode=disable"},
+		{src: "replica=host=db port=5432", wantName: "replica", wantDSN: "host=db port=5432"},
+		{src: "host=db port=5432", wantDSN: "host=db port=5432"},
+		{src: "search_path=app host=db", wantDSN: "search_path=app host=db"},
+		{src: "TimeZone=UTC host=db dbname=app", wantDSN: "TimeZone=UTC host=db dbname=app"},
+		{src: "replica=search_path=app host=db", wantName: "replica", wantDSN: "search_path=app host=db"},
+		{src: "replica=host=db,weight=3", wantName: "replica", wantDSN: "host=db"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			s, err := parseSource(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.name != tt.wantName || s.dsn != tt.wantDSN {
+				t.Fatalf("name %q dsn %q, want name %q dsn %q", s.name, s.dsn, tt.wantName, tt.wantDSN)
+			}
+		})
+	}
+}
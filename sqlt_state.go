@@ -0,0 +1,101 @@
+package sqlt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// stateVersion is increased when the snapshot format change incompatibly
+const stateVersion = 1
+
+// state is the routing state saved by SnapshotState
+type state struct {
+	Version int         `json:"version"`
+	Group   string      `json:"group"`
+	Taken   time.Time   `json:"taken"`
+	Nodes   []nodeState `json:"nodes"`
+}
+
+type nodeState struct {
+	Name      string `json:"name"`
+	Connected bool   `json:"connected"`
+	Error     string `json:"error,omitempty"`
+	Weight    int    `json:"weight"`
+	Disabled  bool   `json:"disabled,omitempty"`
+	// Lag and Backoff are in nanoseconds, Lag is -1 when unknown
+	Lag     int64 `json:"lag"`
+	Backoff int64 `json:"backoff,omitempty"`
+}
+
+// SnapshotState serialize the health, weight, disabled flag and lag of every node, so a restarting
+// process can warm-start its routing with RestoreState instead of waiting for the first heartbeat
+func (db *DB) SnapshotState() ([]byte, error) {
+	db.mu.Lock()
+	s := state{Version: stateVersion, Group: db.groupName, Taken: time.Now()}
+	for _, n := range db.members() {
+		ns := nodeState{
+			Name:      n.name,
+			Connected: n.status.Connected,
+			Weight:    n.weight,
+			Disabled:  n.disabled,
+			Lag:       n.lag.Load(),
+			Backoff:   n.backoff.Load(),
+		}
+		if err, ok := n.status.Error.(error); ok && err != nil {
+			ns.Error = err.Error()
+		}
+		s.Nodes = append(s.Nodes, ns)
+	}
+	db.mu.Unlock()
+	return json.Marshal(s)
+}
+
+// RestoreState apply the state serialized by SnapshotState to the nodes with the same name, nodes
+// missing from the snapshot are left untouched. Nodes down in the snapshot are taken out of rotation
+// until the probe bring them back, the master is still decided by the configuration
+func (db *DB) RestoreState(data []byte) error {
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s.Version != stateVersion {
+		return fmt.Errorf("unsupported state version %d", s.Version)
+	}
+	if s.Group != db.groupName {
+		return fmt.Errorf("state of group %q can't be restored into group %q", s.Group, db.groupName)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	now := time.Now()
+	for _, ns := range s.Nodes {
+		n, err := db.node(ns.Name)
+		if err != nil {
+			continue
+		}
+		if ns.Weight > 0 {
+			n.weight = ns.Weight
+		}
+		n.disabled = ns.Disabled && n.index != db.masterIndex
+		n.lag.Store(ns.Lag)
+		if ns.Connected {
+			continue
+		}
+
+		// the probe need the success threshold to bring the node back, as if it failed the last ping
+		n.checked = true
+		n.status.Connected = false
+		if ns.Error != "" {
+			n.status.Error = errors.New(ns.Error)
+		}
+		n.backoff.Store(ns.Backoff)
+		n.scheduleProbe(now)
+		if !db.isLoadBalancer(n) {
+			n.active = false
+		}
+	}
+	db.updateRouting()
+	return nil
+}
@@ -0,0 +1,30 @@
+package sqlt
+
+import "database/sql"
+
+// Stats return the connection pool statistics of every node, keyed by node name
+func (db *DB) Stats() map[string]sql.DBStats {
+	stats := make(map[string]sql.DBStats)
+	for _, n := range db.members() {
+		stats[n.name] = n.db().Stats()
+	}
+	return stats
+}
+
+// AggregatedStats return the sum of connection pool statistics across all nodes
+func (db *DB) AggregatedStats() sql.DBStats {
+	var total sql.DBStats
+	for _, n := range db.members() {
+		s := n.db().Stats()
+		total.MaxOpenConnections += s.MaxOpenConnections
+		total.OpenConnections += s.OpenConnections
+		total.InUse += s.InUse
+		total.Idle += s.Idle
+		total.WaitCount += s.WaitCount
+		total.WaitDuration += s.WaitDuration
+		total.MaxIdleClosed += s.MaxIdleClosed
+		total.MaxIdleTimeClosed += s.MaxIdleTimeClosed
+		total.MaxLifetimeClosed += s.MaxLifetimeClosed
+	}
+	return total
+}
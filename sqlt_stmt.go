@@ -0,0 +1,385 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Stmt implement sql stmt
+type Stmt struct {
+	db    *DB
+	stmts *stmtSet[*sql.Stmt]
+	// masterOnly statement is only prepared and used on master
+	masterOnly bool
+}
+
+func (st *Stmt) call(op string, args []interface{}, write, master bool) call {
+	return call{op: op, query: st.stmts.query, args: args, write: write, master: master || st.masterOnly}
+}
+
+// Exec will always go to production
+func (st *Stmt) Exec(args ...interface{}) (sql.Result, error) {
+	return st.ExecContext(context.Background(), args...)
+}
+
+// Query will always go to slave
+func (st *Stmt) Query(args ...interface{}) (*sql.Rows, error) {
+	return st.QueryContext(context.Background(), args...)
+}
+
+// QueryMaster will use master db
+func (st *Stmt) QueryMaster(args ...interface{}) (*sql.Rows, error) {
+	return st.QueryMasterContext(context.Background(), args...)
+}
+
+// QueryRow will always go to slave
+func (st *Stmt) QueryRow(args ...interface{}) *sql.Row {
+	return st.QueryRowContext(context.Background(), args...)
+}
+
+// QueryRowMaster will use master db
+func (st *Stmt) QueryRowMaster(args ...interface{}) *sql.Row {
+	return st.QueryRowMasterContext(context.Background(), args...)
+}
+
+// ExecContext will always go to production
+func (st *Stmt) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := st.db.run(ctx, st.call("Stmt.Exec", args, true, false), func(ctx context.Context, n *node, query string) error {
+		stmt, err := st.stmts.get(ctx, n)
+		if err != nil {
+			return err
+		}
+		result, err = stmt.ExecContext(ctx, args...)
+		return err
+	})
+	recordAffected(ctx, result)
+	return result, err
+}
+
+// QueryContext will always go to slave
+func (st *Stmt) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	return st.query(ctx, st.call("Stmt.Query", args, false, false))
+}
+
+// QueryMasterContext will use master db
+func (st *Stmt) QueryMasterContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	return st.query(ctx, st.call("Stmt.QueryMaster", args, false, true))
+}
+
+func (st *Stmt) query(ctx context.Context, c call) (*sql.Rows, error) {
+	c.stream = true
+	var rows *sql.Rows
+	err := st.db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+		stmt, err := st.stmts.stream(ctx, st.db, n)
+		if err != nil {
+			return err
+		}
+		rows, err = stmt.QueryContext(ctx, c.args...)
+		st.db.leaks.track(c, n, rows)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRowContext will always go to slave
+func (st *Stmt) QueryRowContext(ctx context.Context, args ...interface{}) *sql.Row {
+	return st.queryRow(ctx, st.call("Stmt.QueryRow", args, false, false))
+}
+
+// QueryRowMasterContext will use master db
+func (st *Stmt) QueryRowMasterContext(ctx context.Context, args ...interface{}) *sql.Row {
+	return st.queryRow(ctx, st.call("Stmt.QueryRowMaster", args, false, true))
+}
+
+// queryRow fallback to unprepared query when the statement can't be prepared, so the row carry the error
+func (st *Stmt) queryRow(ctx context.Context, c call) *sql.Row {
+	c.stream = true
+	var row *sql.Row
+	st.db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+		stmt, err := st.stmts.stream(ctx, st.db, n)
+		if err != nil {
+			row = st.db.conn(ctx, n).QueryRowContext(ctx, query, c.args...)
+		} else {
+			row = stmt.QueryRowContext(ctx, c.args...)
+		}
+		return row.Err()
+	})
+	if row == nil {
+		row = st.db.nodeAt(st.db.master()).db().QueryRowContext(ctx, c.query, c.args...)
+	}
+	return row
+}
+
+// Close stmt
+func (st *Stmt) Close() error {
+	return st.stmts.close()
+}
+
+// Stmtx implement sqlx stmt
+type Stmtx struct {
+	db    *DB
+	stmts *stmtSet[*sqlx.Stmt]
+	// masterOnly statement is only prepared and used on master
+	masterOnly bool
+}
+
+// Rebind query for the nodes of the statement, master for statements prepared only on master
+func (st *Stmtx) Rebind(query string) string {
+	if st.masterOnly {
+		return st.db.RebindMaster(query)
+	}
+	return st.db.Rebind(query)
+}
+
+// BindType return the bindvar type of the nodes of the statement
+func (st *Stmtx) BindType() int {
+	if st.masterOnly {
+		return st.db.nodeAt(st.db.master()).bind
+	}
+	return st.db.BindType()
+}
+
+func (st *Stmtx) call(op string, args []interface{}, write, master bool) call {
+	return call{op: op, query: st.stmts.query, args: args, write: write, master: master || st.masterOnly}
+}
+
+// run get the statement of the node serving the call and execute fn against it
+func (st *Stmtx) run(ctx context.Context, c call, fn func(ctx context.Context, stmt *sqlx.Stmt) error) error {
+	return st.db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+		stmt, err := st.stmts.get(ctx, n)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, stmt)
+	})
+}
+
+// Close all dbs connection
+func (st *Stmtx) Close() error {
+	return st.stmts.close()
+}
+
+// Exec will always go to production
+func (st *Stmtx) Exec(args ...interface{}) (sql.Result, error) {
+	return st.ExecContext(context.Background(), args...)
+}
+
+// Query will always go to slave
+func (st *Stmtx) Query(args ...interface{}) (*sql.Rows, error) {
+	return st.QueryContext(context.Background(), args...)
+}
+
+// QueryMaster will use master db
+func (st *Stmtx) QueryMaster(args ...interface{}) (*sql.Rows, error) {
+	return st.QueryMasterContext(context.Background(), args...)
+}
+
+// QueryRow will always go to slave
+func (st *Stmtx) QueryRow(args ...interface{}) *sql.Row {
+	return st.QueryRowContext(context.Background(), args...)
+}
+
+// QueryRowMaster will use master db
+func (st *Stmtx) QueryRowMaster(args ...interface{}) *sql.Row {
+	return st.QueryRowMasterContext(context.Background(), args...)
+}
+
+// MustExec using master database
+func (st *Stmtx) MustExec(args ...interface{}) sql.Result {
+	return st.MustExecContext(context.Background(), args...)
+}
+
+// Queryx will always go to slave
+func (st *Stmtx) Queryx(args ...interface{}) (*sqlx.Rows, error) {
+	return st.QueryxContext(context.Background(), args...)
+}
+
+// QueryRowx will always go to slave
+func (st *Stmtx) QueryRowx(args ...interface{}) *sqlx.Row {
+	return st.QueryRowxContext(context.Background(), args...)
+}
+
+// QueryRowxMaster will always go to master
+func (st *Stmtx) QueryRowxMaster(args ...interface{}) *sqlx.Row {
+	return st.QueryRowxMasterContext(context.Background(), args...)
+}
+
+// Get will always go to slave
+func (st *Stmtx) Get(dest interface{}, args ...interface{}) error {
+	return st.GetContext(context.Background(), dest, args...)
+}
+
+// GetMaster will always go to master
+func (st *Stmtx) GetMaster(dest interface{}, args ...interface{}) error {
+	return st.GetMasterContext(context.Background(), dest, args...)
+}
+
+// Select will always go to slave
+func (st *Stmtx) Select(dest interface{}, args ...interface{}) error {
+	return st.SelectContext(context.Background(), dest, args...)
+}
+
+// SelectMaster will always go to master
+func (st *Stmtx) SelectMaster(dest interface{}, args ...interface{}) error {
+	return st.SelectMasterContext(context.Background(), dest, args...)
+}
+
+// ExecContext will always go to production
+func (st *Stmtx) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := st.run(ctx, st.call("Stmtx.Exec", args, true, false), func(ctx context.Context, stmt *sqlx.Stmt) error {
+		var err error
+		result, err = stmt.ExecContext(ctx, args...)
+		return err
+	})
+	recordAffected(ctx, result)
+	return result, err
+}
+
+// QueryContext will always go to slave
+func (st *Stmtx) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	return st.query(ctx, st.call("Stmtx.Query", args, false, false))
+}
+
+// QueryMasterContext will use master db
+func (st *Stmtx) QueryMasterContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	return st.query(ctx, st.call("Stmtx.QueryMaster", args, false, true))
+}
+
+func (st *Stmtx) query(ctx context.Context, c call) (*sql.Rows, error) {
+	c.stream = true
+	var rows *sql.Rows
+	err := st.db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+		stmt, err := st.stmts.stream(ctx, st.db, n)
+		if err != nil {
+			return err
+		}
+		rows, err = stmt.QueryContext(ctx, c.args...)
+		st.db.leaks.track(c, n, rows)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRowContext will always go to slave
+func (st *Stmtx) QueryRowContext(ctx context.Context, args ...interface{}) *sql.Row {
+	return st.queryRow(ctx, st.call("Stmtx.QueryRow", args, false, false))
+}
+
+// QueryRowMasterContext will use master db
+func (st *Stmtx) QueryRowMasterContext(ctx context.Context, args ...interface{}) *sql.Row {
+	return st.queryRow(ctx, st.call("Stmtx.QueryRowMaster", args, false, true))
+}
+
+// queryRow fallback to unprepared query when the statement can't be prepared, so the row carry the error
+func (st *Stmtx) queryRow(ctx context.Context, c call) *sql.Row {
+	c.stream = true
+	var row *sql.Row
+	st.db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+		stmt, err := st.stmts.stream(ctx, st.db, n)
+		if err != nil {
+			row = st.db.conn(ctx, n).QueryRowContext(ctx, query, c.args...)
+		} else {
+			row = stmt.QueryRowContext(ctx, c.args...)
+		}
+		return row.Err()
+	})
+	if row == nil {
+		row = st.db.nodeAt(st.db.master()).db().QueryRowContext(ctx, c.query, c.args...)
+	}
+	return row
+}
+
+// MustExecContext using master database
+func (st *Stmtx) MustExecContext(ctx context.Context, args ...interface{}) sql.Result {
+	result, err := st.ExecContext(ctx, args...)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// QueryxContext will always go to slave
+func (st *Stmtx) QueryxContext(ctx context.Context, args ...interface{}) (*sqlx.Rows, error) {
+	c := st.call("Stmtx.Queryx", args, false, false)
+	c.stream = true
+	var rows *sqlx.Rows
+	err := st.db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+		stmt, err := st.stmts.stream(ctx, st.db, n)
+		if err != nil {
+			return err
+		}
+		rows, err = stmt.QueryxContext(ctx, args...)
+		if err == nil {
+			st.db.leaks.track(c, n, rows.Rows)
+		}
+		return err
+	})
+	return rows, err
+}
+
+// QueryRowxContext will always go to slave
+func (st *Stmtx) QueryRowxContext(ctx context.Context, args ...interface{}) *sqlx.Row {
+	return st.queryRowx(ctx, st.call("Stmtx.QueryRowx", args, false, false))
+}
+
+// QueryRowxMasterContext will always go to master
+func (st *Stmtx) QueryRowxMasterContext(ctx context.Context, args ...interface{}) *sqlx.Row {
+	return st.queryRowx(ctx, st.call("Stmtx.QueryRowxMaster", args, false, true))
+}
+
+// queryRowx fallback to unprepared query when the statement can't be prepared, so the row carry the error
+func (st *Stmtx) queryRowx(ctx context.Context, c call) *sqlx.Row {
+	c.stream = true
+	var row *sqlx.Row
+	st.db.run(ctx, c, func(ctx context.Context, n *node, query string) error {
+		stmt, err := st.stmts.stream(ctx, st.db, n)
+		if err != nil {
+			row = st.db.conn(ctx, n).QueryRowxContext(ctx, query, c.args...)
+		} else {
+			row = stmt.QueryRowxContext(ctx, c.args...)
+		}
+		return row.Err()
+	})
+	if row == nil {
+		row = st.db.nodeAt(st.db.master()).db().QueryRowxContext(ctx, c.query, c.args...)
+	}
+	return row
+}
+
+// GetContext will always go to slave
+func (st *Stmtx) GetContext(ctx context.Context, dest interface{}, args ...interface{}) error {
+	return st.get(ctx, st.call("Stmtx.Get", args, false, false), dest)
+}
+
+// GetMasterContext will always go to master
+func (st *Stmtx) GetMasterContext(ctx context.Context, dest interface{}, args ...interface{}) error {
+	return st.get(ctx, st.call("Stmtx.GetMaster", args, false, true), dest)
+}
+
+func (st *Stmtx) get(ctx context.Context, c call, dest interface{}) error {
+	c.dest = dest
+	return st.run(ctx, c, func(ctx context.Context, stmt *sqlx.Stmt) error {
+		return stmt.GetContext(ctx, dest, c.args...)
+	})
+}
+
+// SelectContext will always go to slave
+func (st *Stmtx) SelectContext(ctx context.Context, dest interface{}, args ...interface{}) error {
+	return st.selectRows(ctx, st.call("Stmtx.Select", args, false, false), dest)
+}
+
+// SelectMasterContext will always go to master
+func (st *Stmtx) SelectMasterContext(ctx context.Context, dest interface{}, args ...interface{}) error {
+	return st.selectRows(ctx, st.call("Stmtx.SelectMaster", args, false, true), dest)
+}
+
+func (st *Stmtx) selectRows(ctx context.Context, c call, dest interface{}) error {
+	c.dest = dest
+	return st.run(ctx, c, func(ctx context.Context, stmt *sqlx.Stmt) error {
+		return stmt.SelectContext(ctx, dest, c.args...)
+	})
+}
@@ -0,0 +1,167 @@
+package sqlt
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// WithStmtCache execute repeated queries through prepared statements cached per node,
+// the least recently used statement is closed when the cache hold more than maxEntries queries.
+// The cache is disabled by WithQueryComments and skipped by streamed rows, e.g. Query and QueryRow
+func WithStmtCache(maxEntries int) Option {
+	return func(o *options) {
+		o.stmtCacheSize = maxEntries
+	}
+}
+
+// stmtCache is an LRU of statements keyed by query
+type stmtCache struct {
+	max   int
+	nodes int
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+// cachedStmt is released by its last user after eviction
+type cachedStmt struct {
+	query   string
+	stmts   *stmtSet[*sqlx.Stmt]
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(max, nodes int) *stmtCache {
+	return &stmtCache{
+		max:     max,
+		nodes:   nodes,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// acquire return the statement of the query, the caller must release it
+func (c *stmtCache) acquire(query string) *cachedStmt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[query]; ok {
+		c.lru.MoveToFront(el)
+		s := el.Value.(*cachedStmt)
+		s.refs++
+		return s
+	}
+
+	s := &cachedStmt{query: query, stmts: newStmtSet(c.nodes, query, preparexStmt), refs: 1}
+	c.entries[query] = c.lru.PushFront(s)
+	for c.lru.Len() > c.max {
+		oldest := c.lru.Back()
+		evicted := c.lru.Remove(oldest).(*cachedStmt)
+		delete(c.entries, evicted.query)
+		evicted.evicted = true
+		if evicted.refs == 0 {
+			evicted.stmts.close()
+		}
+	}
+	return s
+}
+
+func (c *stmtCache) release(s *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.refs--
+	if s.evicted && s.refs == 0 {
+		s.stmts.close()
+	}
+}
+
+// prepare every cached statement on the node, statements failing to prepare are prepared
+// again on first use
+func (c *stmtCache) prepare(ctx context.Context, n *node) {
+	c.mu.Lock()
+	sets := make([]*stmtSet[*sqlx.Stmt], 0, len(c.entries))
+	for _, el := range c.entries {
+		sets = append(sets, el.Value.(*cachedStmt).stmts)
+	}
+	c.mu.Unlock()
+
+	for _, s := range sets {
+		s.get(ctx, n)
+	}
+}
+
+// close every cached statement
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for query, el := range c.entries {
+		el.Value.(*cachedStmt).stmts.close()
+		delete(c.entries, query)
+	}
+	c.lru.Init()
+}
+
+// queryer is the query methods shared by the node connection and the cached statement
+type queryer interface {
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+	QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// preparedQueryer run the query methods through a prepared statement, the query is ignored
+type preparedQueryer struct {
+	stmt *sqlx.Stmt
+}
+
+func (p preparedQueryer) SelectContext(ctx context.Context, dest interface{}, _ string, args ...interface{}) error {
+	return p.stmt.SelectContext(ctx, dest, args...)
+}
+
+func (p preparedQueryer) GetContext(ctx context.Context, dest interface{}, _ string, args ...interface{}) error {
+	return p.stmt.GetContext(ctx, dest, args...)
+}
+
+func (p preparedQueryer) QueryContext(ctx context.Context, _ string, args ...interface{}) (*sql.Rows, error) {
+	return p.stmt.QueryContext(ctx, args...)
+}
+
+func (p preparedQueryer) QueryRowContext(ctx context.Context, _ string, args ...interface{}) *sql.Row {
+	return p.stmt.QueryRowContext(ctx, args...)
+}
+
+func (p preparedQueryer) QueryxContext(ctx context.Context, _ string, args ...interface{}) (*sqlx.Rows, error) {
+	return p.stmt.QueryxContext(ctx, args...)
+}
+
+func (p preparedQueryer) QueryRowxContext(ctx context.Context, _ string, args ...interface{}) *sqlx.Row {
+	return p.stmt.QueryRowxContext(ctx, args...)
+}
+
+func (p preparedQueryer) ExecContext(ctx context.Context, _ string, args ...interface{}) (sql.Result, error) {
+	return p.stmt.ExecContext(ctx, args...)
+}
+
+// queryer return the cached statement of the query on the node, or the node connection when
+// the cache is disabled or the statement can't be prepared. Streamed calls skip the cache, their
+// rows must hold the connection reserved by the call. done must be called after use
+func (db *DB) queryer(ctx context.Context, n *node, query string) (q queryer, done func()) {
+	if db.stmtCache == nil || streamed(ctx) {
+		return db.conn(ctx, n), func() {}
+	}
+	s := db.stmtCache.acquire(query)
+	stmt, err := s.stmts.get(ctx, n)
+	if err != nil {
+		db.stmtCache.release(s)
+		return db.conn(ctx, n), func() {}
+	}
+	return preparedQueryer{stmt: stmt}, func() { db.stmtCache.release(s) }
+}
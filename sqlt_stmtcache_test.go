@@ -0,0 +1,92 @@
+package sqlt
+
+import (
+	"slices"
+	"testing"
+)
+
+// cachedQueries return the queries of the statement cache, most recently used first
+func cachedQueries(db *DB) []string {
+	db.stmtCache.mu.Lock()
+	defer db.stmtCache.mu.Unlock()
+	var queries []string
+	for el := db.stmtCache.lru.Front(); el != nil; el = el.Next() {
+		queries = append(queries, el.Value.(*cachedStmt).query)
+	}
+	return queries
+}
+
+func TestStmtCache(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		run  func(db *DB) error
+		want []string
+	}{
+		{
+			name: "exec",
+			size: 8,
+			run: func(db *DB) error {
+				_, err := db.Exec("UPDATE users SET name = ?", "a")
+				return err
+			},
+			want: []string{"UPDATE users SET name = ?"},
+		},
+		{
+			name: "named exec",
+			size: 8,
+			run: func(db *DB) error {
+				_, err := db.NamedExec("UPDATE users SET name = :name", map[string]interface{}{"name": "a"})
+				return err
+			},
+			want: []string{"UPDATE users SET name = ?"},
+		},
+		{
+			name: "read",
+			size: 8,
+			run: func(db *DB) error {
+				var names []string
+				return db.Select(&names, "SELECT name FROM users")
+			},
+			want: []string{"SELECT name FROM users"},
+		},
+		{
+			name: "streamed read skip the cache",
+			size: 8,
+			run: func(db *DB) error {
+				rows, err := db.Query("SELECT name FROM users")
+				if err == nil {
+					rows.Close()
+				}
+				return err
+			},
+		},
+		{
+			name: "least recently used evicted",
+			size: 2,
+			run: func(db *DB) error {
+				for _, query := range []string{"DELETE FROM a", "DELETE FROM b", "DELETE FROM a", "DELETE FROM c"} {
+					if _, err := db.Exec(query); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			want: []string{"DELETE FROM c", "DELETE FROM a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDB(t, 1, WithStmtCache(tt.size))
+			// run twice, the statement is prepared once
+			for i := 0; i < 2; i++ {
+				if err := tt.run(db); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := cachedQueries(db); !slices.Equal(got, tt.want) {
+				t.Fatalf("cached %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package sqlt
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// stream hold the connection reserved for the rows of a streamed call. database/sql has no hook
+// on Rows.Close, but closing the connection block until its rows are closed
+type stream struct {
+	conn *sqlx.Conn
+	// stmt prepared on the connection, closed once the rows are closed
+	stmt *sqlx.Stmt
+}
+
+type streamKey struct{}
+
+// stream return the context passed to fn of the call, a streamed call carry its stream
+func (db *DB) stream(ctx context.Context, c call) (context.Context, *stream) {
+	if !c.stream {
+		return ctx, nil
+	}
+	s := &stream{}
+	return context.WithValue(ctx, streamKey{}, s), s
+}
+
+// streamed return true when ctx is the context of a streamed call
+func streamed(ctx context.Context) bool {
+	s, _ := ctx.Value(streamKey{}).(*stream)
+	return s != nil
+}
+
+// conn return the connection running the query on the node. A streamed call reserve a connection,
+// so the slot of the call is only released once its rows are closed
+func (db *DB) conn(ctx context.Context, n *node) queryer {
+	s, _ := ctx.Value(streamKey{}).(*stream)
+	if s == nil || s.conn != nil {
+		return n.db()
+	}
+	conn, err := n.db().Connx(ctx)
+	if err != nil {
+		// the query fail the same way on the pool
+		return n.db()
+	}
+	s.conn = conn
+	return conn
+}
+
+// prepare the query of a streamed call on a reserved connection, a statement prepared on the pool
+// release its connection with the rows and nothing would tell the stream they are closed.
+// The statement is closed with the stream
+func (db *DB) prepare(ctx context.Context, n *node, query string) (*sqlx.Stmt, error) {
+	s := ctx.Value(streamKey{}).(*stream)
+	conn, err := n.db().Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := conn.PreparexContext(ctx, query)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	s.conn, s.stmt = conn, stmt
+	return stmt, nil
+}
+
+// close call release, which free the slot and the contexts of the call, once the rows of the stream
+// are closed, immediately when the call didn't reserve a connection, e.g. a failed query
+func (s *stream) close(release func()) {
+	if s == nil || s.conn == nil {
+		release()
+		return
+	}
+	go func() {
+		s.conn.Close()
+		if s.stmt != nil {
+			s.stmt.Close()
+		}
+		release()
+	}()
+}
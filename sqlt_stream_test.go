@@ -0,0 +1,104 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestStreamedRows(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []Option
+		query func(db *DB) (*sql.Rows, error)
+	}{
+		{
+			name:  "query",
+			query: func(db *DB) (*sql.Rows, error) { return db.Query("SELECT dsn", 3) },
+		},
+		{
+			name: "query through the statement cache",
+			opts: []Option{WithStmtCache(8)},
+			query: func(db *DB) (*sql.Rows, error) {
+				// the statement is cached by a first read
+				var dsn string
+				if err := db.Get(&dsn, "SELECT dsn", 1); err != nil {
+					return nil, err
+				}
+				return db.Query("SELECT dsn", 3)
+			},
+		},
+		{
+			name: "prepared statement",
+			query: func(db *DB) (*sql.Rows, error) {
+				stmt, err := db.Prepare("SELECT dsn")
+				if err != nil {
+					return nil, err
+				}
+				return stmt.Query(3)
+			},
+		},
+		{
+			name: "prepared sqlx statement",
+			query: func(db *DB) (*sql.Rows, error) {
+				stmt, err := db.Preparex("SELECT dsn")
+				if err != nil {
+					return nil, err
+				}
+				rows, err := stmt.Queryx(3)
+				if err != nil {
+					return nil, err
+				}
+				return rows.Rows, nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDB(t, 1, append(tt.opts, WithDefaultQueryTimeout(time.Minute))...)
+			n, err := db.node("slave-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rows, err := tt.query(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the query context is only canceled once the rows are closed
+			time.Sleep(10 * time.Millisecond)
+			count := 0
+			for rows.Next() {
+				count++
+			}
+			if err := rows.Err(); err != nil || count != 3 {
+				t.Fatalf("rows %d, err %v, want 3 rows", count, err)
+			}
+			if got := n.inflight.Load(); got != 1 {
+				t.Fatalf("inflight %d with the rows open, want 1", got)
+			}
+
+			rows.Close()
+			deadline := time.Now().Add(time.Second)
+			for n.inflight.Load() != 0 {
+				if time.Now().After(deadline) {
+					t.Fatal("slot not released once the rows are closed")
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestStreamedRowClosedStmt(t *testing.T) {
+	db := newMockDB(t, 1)
+	stmt, err := db.Preparex("SELECT dsn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+	if _, err := stmt.QueryContext(context.Background()); err == nil {
+		t.Fatal("closed statement queried")
+	}
+}
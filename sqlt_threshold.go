@@ -0,0 +1,49 @@
+package sqlt
+
+import "time"
+
+// HealthThresholds make node ejection and recovery tolerant to transient ping results
+type HealthThresholds struct {
+	// Failures is the number of consecutive failed pings ejecting a node, default to 1
+	Failures int
+	// Successes is the number of consecutive successful pings restoring a node, default to 1
+	Successes int
+	// MaxBackoff cap the exponential backoff of the probes to a down node, starting from
+	// twice its ping interval. Default to 30 seconds
+	MaxBackoff time.Duration
+}
+
+// WithHealthThresholds set the consecutive ping results needed to eject and restore a node,
+// the first ping of a node always apply
+func WithHealthThresholds(t HealthThresholds) Option {
+	return func(o *options) {
+		o.thresholds = t
+	}
+}
+
+// count the consecutive ping results of the node, must be called with DB.mu held.
+// Return whether the result is applied to the health of the node
+func (db *DB) count(n *node, check healthCheck) bool {
+	first := !n.checked
+	n.checked = true
+
+	if check.err == nil {
+		n.failures = 0
+		n.successes++
+		n.backoff.Store(0)
+		return first || n.status.Connected || n.successes >= max(db.opts.thresholds.Successes, 1)
+	}
+
+	n.successes = 0
+	n.failures++
+	if !n.status.Connected {
+		backoff := max(time.Duration(n.backoff.Load()), n.pingInterval) * 2
+		n.backoff.Store(int64(min(backoff, db.maxProbeBackoff())))
+	}
+	return first || !n.status.Connected || n.failures >= max(db.opts.thresholds.Failures, 1)
+}
+
+// probeInterval return the interval between the pings of the node, including its backoff
+func (n *node) probeInterval() time.Duration {
+	return max(n.pingInterval, time.Duration(n.backoff.Load()))
+}
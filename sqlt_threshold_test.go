@@ -0,0 +1,125 @@
+package sqlt
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// nodeConnected return the health of the named node, without the ping of GetStatus
+func nodeConnected(t *testing.T, db *DB, name string) bool {
+	t.Helper()
+	for _, s := range db.status() {
+		if s.Name == name {
+			return s.Connected
+		}
+	}
+	t.Fatalf("node %s not found", name)
+	return false
+}
+
+func TestHealthThresholds(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds HealthThresholds
+		// pings of the slave, true when the ping succeed
+		pings []bool
+		// want is the health of the slave after every ping
+		want []bool
+	}{
+		{
+			name:  "default apply every ping",
+			pings: []bool{false, true, false, true},
+			want:  []bool{false, true, false, true},
+		},
+		{
+			name:       "eject after consecutive failures",
+			thresholds: HealthThresholds{Failures: 3},
+			pings:      []bool{true, false, false, true, false, false, false},
+			want:       []bool{true, true, true, true, true, true, false},
+		},
+		{
+			name:       "restore after consecutive successes",
+			thresholds: HealthThresholds{Failures: 1, Successes: 2},
+			pings:      []bool{true, false, true, false, true, true},
+			want:       []bool{true, false, false, false, false, true},
+		},
+		{
+			name:       "first ping always apply",
+			thresholds: HealthThresholds{Failures: 3, Successes: 3},
+			pings:      []bool{false, true, true, true},
+			want:       []bool{false, false, false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDB(t, 2, WithHealthThresholds(tt.thresholds))
+			for i, ok := range tt.pings {
+				if ok {
+					db.RecoverNode("slave-1")
+				} else {
+					db.FailNode("slave-1", errors.New("node down"))
+				}
+				db.Ping()
+				if got := nodeConnected(t, db, "slave-1"); got != tt.want[i] {
+					t.Fatalf("ping %d: connected %v, want %v", i+1, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProbeBackoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		interval   time.Duration
+		maxBackoff time.Duration
+		// want is the probe interval of the slave after every failed ping
+		want []time.Duration
+	}{
+		{
+			name:       "doubled from the ping interval",
+			interval:   time.Second,
+			maxBackoff: time.Minute,
+			want:       []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		{
+			name:       "capped by max backoff",
+			interval:   time.Second,
+			maxBackoff: 3 * time.Second,
+			want:       []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDB(t, 2, WithHealthThresholds(HealthThresholds{MaxBackoff: tt.maxBackoff}), WithPingInterval("slave-1", tt.interval))
+			n, err := db.node("slave-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			db.FailNode("slave-1", errors.New("node down"))
+			for i, want := range tt.want {
+				db.Ping()
+				if got := n.probeInterval(); got != want {
+					t.Fatalf("failure %d: probe interval %v, want %v", i+1, got, want)
+				}
+			}
+
+			// the down node is only probed once its backoff elapsed
+			now := time.Now()
+			if due := db.downNodes(now); len(due) != 0 {
+				t.Fatalf("probe due before the backoff: %v", len(due))
+			}
+			if due := db.downNodes(now.Add(tt.want[len(tt.want)-1] + time.Millisecond)); len(due) != 1 || due[0] != n {
+				t.Fatalf("probe not due after the backoff: %v", len(due))
+			}
+
+			db.RecoverNode("slave-1")
+			db.Ping()
+			if got := n.probeInterval(); got != tt.interval || n.nextProbe.Load() != 0 {
+				t.Fatalf("backoff %v and next probe %d kept after recovery", got, n.nextProbe.Load())
+			}
+		})
+	}
+}
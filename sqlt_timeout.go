@@ -0,0 +1,36 @@
+package sqlt
+
+import (
+	"context"
+	"time"
+)
+
+// WithDefaultQueryTimeout bound every query which context has no deadline
+func WithDefaultQueryTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.queryTimeout = d
+	}
+}
+
+// WithNodeQueryTimeout override the default query timeout of the named node,
+// e.g. slaves serving analytics can tolerate longer queries than master
+func WithNodeQueryTimeout(name string, d time.Duration) Option {
+	return func(o *options) {
+		o.queryTimeouts[name] = d
+	}
+}
+
+// queryContext apply the query timeout of the node unless ctx already has a deadline
+func (db *DB) queryContext(ctx context.Context, n *node) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	timeout := db.opts.queryTimeout
+	if d, ok := db.opts.queryTimeouts[n.name]; ok {
+		timeout = d
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
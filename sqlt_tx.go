@@ -0,0 +1,179 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RetryPolicy describe how a transaction should be retried when it fails with retryable error
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+	// Backoff is the wait time before the first retry, doubled on every next retry
+	Backoff time.Duration
+	// MaxBackoff cap the wait time between retries, zero means no cap
+	MaxBackoff time.Duration
+	// Jitter randomize the wait time by up to the given fraction, e.g. 0.2 wait 80% to 120% of the backoff
+	Jitter float64
+	// Retryable decide whether an error can be retried, IsRetryable is used when nil
+	Retryable func(error) bool
+	// OnRetry is called with the attempt number and its error before every retry
+	OnRetry func(attempt int, err error)
+}
+
+// DefaultRetryPolicy retry serialization failures and deadlocks up to three times
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	Backoff:     time.Millisecond * 50,
+	MaxBackoff:  time.Second,
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			d = p.MaxBackoff
+			break
+		}
+	}
+	if p.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + p.Jitter*(2*rand.Float64()-1)))
+	}
+	return d
+}
+
+// mysqlRetryableRegexp match the message of go-sql-driver/mysql deadlock and lock wait timeout errors,
+// "Error 1213 (40001): ..." or "Error 1213: ..." before the sql state was added
+var mysqlRetryableRegexp = regexp.MustCompile(`^Error (1213|1205)( \([0-9A-Z]{5}\))?: `)
+
+// IsRetryable report whether err is a serialization failure or deadlock that is safe to retry
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// pgx and other drivers expose the sqlstate directly
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) && retryableState(state.SQLState()) {
+		return true
+	}
+	if retryableFields(err) {
+		return true
+	}
+
+	// error converted to string by a wrapper, only the exact mysql driver format is trusted
+	for ; err != nil; err = errors.Unwrap(err) {
+		if mysqlRetryableRegexp.MatchString(err.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryableState report whether the sqlstate is a serialization failure or a deadlock
+func retryableState(code string) bool {
+	return code == "40001" || code == "40P01"
+}
+
+// retryableFields report whether an error of the chain has a retryable sqlstate Code field, like lib/pq
+// errors, or a retryable Number field, like go-sql-driver/mysql errors. The drivers are not imported
+// so errors of other types may have the fields, the chain is walked until a retryable value is found
+func retryableFields(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		v := reflect.Indirect(reflect.ValueOf(err))
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		if f := v.FieldByName("Code"); f.IsValid() && f.Kind() == reflect.String && retryableState(f.String()) {
+			return true
+		}
+		if f := v.FieldByName("Number"); f.IsValid() && f.CanUint() && (f.Uint() == 1213 || f.Uint() == 1205) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTxRetryPolicy set the retry policy used by InTx, zero value disable the retry.
+// Transactions already running keep the policy they started with
+func (db *DB) SetTxRetryPolicy(policy RetryPolicy) {
+	db.txRetry.Store(&policy)
+}
+
+// InTx run fn inside a master transaction, the transaction is committed when fn return nil
+// and rolled back otherwise. Retryable errors restart the whole transaction based on the tx retry policy,
+// unless the context is created by WithoutRetry
+func (db *DB) InTx(ctx context.Context, opts *sql.TxOptions, fn func(*sqlx.Tx) error) error {
+	return db.Transact(ctx, opts, func(tx *Tx) error {
+		return fn(tx.Tx)
+	})
+}
+
+// retry call fn until it succeed, fail with error the policy doesn't retry, or run out of attempts
+func (db *DB) retry(ctx context.Context, policy RetryPolicy, fn func() error) error {
+	if retryDisabled(ctx) {
+		policy.MaxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			return err
+		}
+
+		db.report.retry()
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, err)
+		}
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// WithWriteRetry retry Exec and NamedExec on retryable errors such as deadlocks and serialization
+// failures, the policy is also the default transaction retry policy of InTx
+func WithWriteRetry(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.writeRetry = policy
+	}
+}
+
+func (db *DB) inTx(ctx context.Context, opts *sql.TxOptions, fn func(*Tx) error) (err error) {
+	tx, err := db.BeginTxx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
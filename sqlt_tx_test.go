@@ -0,0 +1,137 @@
+package sqlt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pqError has the sqlstate Code field of lib/pq errors
+type pqError struct{ Code string }
+
+func (e *pqError) Error() string { return "pq: " + e.Code }
+
+// mysqlError has the Number field of go-sql-driver/mysql errors
+type mysqlError struct{ Number uint16 }
+
+func (e *mysqlError) Error() string { return fmt.Sprintf("Error %d: mysql", e.Number) }
+
+// codedError has a Code field unknown to IsRetryable and wrap another error
+type codedError struct {
+	Code string
+	err  error
+}
+
+func (e *codedError) Error() string { return e.Code + ": " + e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+// pgxError expose the sqlstate like pgconn.PgError
+type pgxError struct{ code string }
+
+func (e *pgxError) Error() string    { return "pgx: " + e.code }
+func (e *pgxError) SQLState() string { return e.code }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "serialization failure sqlstate", err: &pgxError{code: "40001"}, want: true},
+		{name: "deadlock sqlstate", err: &pgxError{code: "40P01"}, want: true},
+		{name: "unique violation sqlstate", err: &pgxError{code: "23505"}, want: false},
+		{name: "lib/pq serialization failure", err: &pqError{Code: "40001"}, want: true},
+		{name: "lib/pq unique violation", err: &pqError{Code: "23505"}, want: false},
+		{name: "mysql deadlock", err: &mysqlError{Number: 1213}, want: true},
+		{name: "mysql lock wait timeout", err: &mysqlError{Number: 1205}, want: true},
+		{name: "mysql duplicate entry", err: &mysqlError{Number: 1062}, want: false},
+		{name: "wrapped deadlock", err: fmt.Errorf("update stock: %w", &mysqlError{Number: 1213}), want: true},
+		{name: "mysql deadlock message", err: errors.New("Error 1213 (40001): Deadlock found when trying to get lock"), want: true},
+		{name: "mysql lock wait timeout message", err: errors.New("Error 1205: Lock wait timeout exceeded"), want: true},
+		{name: "mysql message wrapped", err: fmt.Errorf("update stock: %w", errors.New("Error 1213 (40001): Deadlock found")), want: true},
+		{name: "mysql code inside another message", err: errors.New("update stock: Error 1213 (40001): Deadlock found"), want: false},
+		{name: "sqlstate inside a message", err: errors.New("value 40001 out of range"), want: false},
+		{name: "unknown code wrapping a deadlock", err: &codedError{Code: "E42", err: &mysqlError{Number: 1213}}, want: true},
+		{name: "unknown code wrapping a deadlock message", err: &codedError{Code: "E42", err: errors.New("Error 1213 (40001): Deadlock found")}, want: true},
+		{name: "unknown code", err: &codedError{Code: "E42", err: errors.New("failed")}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Fatalf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransactRetry(t *testing.T) {
+	errDeadlock := &pgxError{code: "40P01"}
+	errConstraint := &pgxError{code: "23505"}
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+
+	tests := []struct {
+		name   string
+		policy *RetryPolicy
+		// errs returned by the attempts in order, the following attempts succeed
+		errs         []error
+		wantAttempts int
+		wantErr      error
+	}{
+		{name: "success", wantAttempts: 1},
+		{name: "retried until success", errs: []error{errDeadlock, errDeadlock}, wantAttempts: 3},
+		{name: "attempts exhausted", errs: []error{errDeadlock, errDeadlock, errDeadlock}, wantAttempts: 3, wantErr: errDeadlock},
+		{name: "not retryable", errs: []error{errConstraint}, wantAttempts: 1, wantErr: errConstraint},
+		{name: "retry disabled", policy: &RetryPolicy{}, errs: []error{errDeadlock}, wantAttempts: 1, wantErr: errDeadlock},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDB(t, 1, WithWriteRetry(policy))
+			if tt.policy != nil {
+				db.SetTxRetryPolicy(*tt.policy)
+			}
+
+			attempts := 0
+			err := db.Transact(context.Background(), nil, func(tx *Tx) error {
+				attempts++
+				if _, err := tx.Exec("UPDATE stock SET quantity = quantity - 1"); err != nil {
+					return err
+				}
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Fatalf("attempts %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestSetTxRetryPolicyConcurrent(t *testing.T) {
+	db := newMockDB(t, 1, WithWriteRetry(RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				db.Transact(context.Background(), nil, func(tx *Tx) error {
+					return &pgxError{code: "40001"}
+				})
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		db.SetTxRetryPolicy(RetryPolicy{MaxAttempts: i%3 + 1, Backoff: time.Millisecond})
+	}
+	wg.Wait()
+}
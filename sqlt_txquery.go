@@ -0,0 +1,229 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// txWrite is a write run in the transaction, the cache hooks are notified once it is committed
+type txWrite struct {
+	ctx context.Context
+	c   call
+}
+
+// run execute fn in the transaction with the comments, logging and metrics of DB.run
+func (tx *Tx) run(ctx context.Context, c call, fn func(ctx context.Context, query string) error) error {
+	ctx = tx.db.decorate(ctx)
+	ctx, unwatch, cancel := tx.db.watchdog.watch(ctx, c, tx.n)
+	if c.stream {
+		// the rows are closed with the transaction
+		tx.cancels = append(tx.cancels, cancel)
+	} else {
+		defer cancel()
+	}
+	start := time.Now()
+	err := fn(ctx, tx.db.comment(ctx, tx.n, tx.db.rebind(tx.n, c.query)))
+	unwatch()
+	err = tx.n.track(err)
+	elapsed := time.Since(start)
+	tx.db.observe(tx.n, elapsed)
+	tx.db.queryStats.record(c.query, tx.n, elapsed, err)
+	tx.db.logSlowQuery(c, tx.n, elapsed)
+	if err == nil && c.write {
+		tx.writes = append(tx.writes, txWrite{ctx: ctx, c: c})
+	}
+	recordMetadata(ctx, c, tx.n, elapsed, err)
+	return wrapError(err, tx.n, c.op, c.query)
+}
+
+// Commit the transaction, the cache hooks are notified of the writes of the transaction
+func (tx *Tx) Commit() error {
+	defer tx.release()
+	if err := tx.Tx.Commit(); err != nil {
+		return err
+	}
+	for _, w := range tx.writes {
+		tx.db.afterWrite(w.ctx, w.c)
+		tx.db.invalidateCache(w.ctx, w.c)
+	}
+	tx.writes = nil
+	tx.db.capturePosition(tx.ctx, tx.n)
+	return nil
+}
+
+// Rollback the transaction
+func (tx *Tx) Rollback() error {
+	defer tx.release()
+	return tx.Tx.Rollback()
+}
+
+// release the contexts of the streamed queries once the transaction is done
+func (tx *Tx) release() {
+	for _, cancel := range tx.cancels {
+		cancel()
+	}
+	tx.cancels = nil
+}
+
+// Exec the query in the transaction
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext the query in the transaction
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := tx.run(ctx, call{op: "Tx.Exec", query: query, args: args, write: true}, func(ctx context.Context, query string) error {
+		var err error
+		result, err = tx.Tx.ExecContext(ctx, query, args...)
+		return err
+	})
+	recordAffected(ctx, result)
+	return result, err
+}
+
+// NamedExec the named query in the transaction
+func (tx *Tx) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	return tx.NamedExecContext(context.Background(), query, arg)
+}
+
+// NamedExecContext the named query in the transaction
+func (tx *Tx) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := tx.run(ctx, call{op: "Tx.NamedExec", query: query, args: []interface{}{arg}, write: true}, func(ctx context.Context, query string) error {
+		query, args, err := tx.BindNamed(query, arg)
+		if err != nil {
+			return err
+		}
+		result, err = tx.Tx.ExecContext(ctx, query, args...)
+		return err
+	})
+	recordAffected(ctx, result)
+	return result, err
+}
+
+// Get a single row in the transaction
+func (tx *Tx) Get(dest interface{}, query string, args ...interface{}) error {
+	return tx.GetContext(context.Background(), dest, query, args...)
+}
+
+// GetContext a single row in the transaction
+func (tx *Tx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return tx.run(ctx, call{op: "Tx.Get", query: query, args: args, dest: dest}, func(ctx context.Context, query string) error {
+		return tx.Tx.GetContext(ctx, dest, query, args...)
+	})
+}
+
+// Select rows in the transaction
+func (tx *Tx) Select(dest interface{}, query string, args ...interface{}) error {
+	return tx.SelectContext(context.Background(), dest, query, args...)
+}
+
+// SelectContext rows in the transaction
+func (tx *Tx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return tx.run(ctx, call{op: "Tx.Select", query: query, args: args, dest: dest}, func(ctx context.Context, query string) error {
+		return tx.Tx.SelectContext(ctx, dest, query, args...)
+	})
+}
+
+// Query rows in the transaction
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext rows in the transaction
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	c := call{op: "Tx.Query", query: query, args: args, stream: true}
+	err := tx.run(ctx, c, func(ctx context.Context, query string) error {
+		var err error
+		rows, err = tx.Tx.QueryContext(ctx, query, args...)
+		tx.db.leaks.track(c, tx.n, rows)
+		return err
+	})
+	return rows, err
+}
+
+// Queryx rows in the transaction
+func (tx *Tx) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+	return tx.QueryxContext(context.Background(), query, args...)
+}
+
+// QueryxContext rows in the transaction
+func (tx *Tx) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	var rows *sqlx.Rows
+	c := call{op: "Tx.Queryx", query: query, args: args, stream: true}
+	err := tx.run(ctx, c, func(ctx context.Context, query string) error {
+		var err error
+		rows, err = tx.Tx.QueryxContext(ctx, query, args...)
+		if err == nil {
+			tx.db.leaks.track(c, tx.n, rows.Rows)
+		}
+		return err
+	})
+	return rows, err
+}
+
+// Rebind query for the driver of the transaction node
+func (tx *Tx) Rebind(query string) string {
+	return tx.n.rebind(query)
+}
+
+// BindNamed bind the named query with arg using the bindvar of the transaction node
+func (tx *Tx) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	return tx.n.bindNamed(query, arg)
+}
+
+// In expand slice arguments of the query with sqlx.In and rebind it for the transaction driver
+func (tx *Tx) In(query string, args ...interface{}) (string, []interface{}, error) {
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return "", nil, err
+	}
+	return tx.Rebind(query), args, nil
+}
+
+// SelectIn expand slice arguments and select in the transaction
+func (tx *Tx) SelectIn(dest interface{}, query string, args ...interface{}) error {
+	return tx.SelectInContext(context.Background(), dest, query, args...)
+}
+
+// SelectInContext expand slice arguments and select in the transaction
+func (tx *Tx) SelectInContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	query, args, err := tx.In(query, args...)
+	if err != nil {
+		return err
+	}
+	return tx.SelectContext(ctx, dest, query, args...)
+}
+
+// GetIn expand slice arguments and get in the transaction
+func (tx *Tx) GetIn(dest interface{}, query string, args ...interface{}) error {
+	return tx.GetInContext(context.Background(), dest, query, args...)
+}
+
+// GetInContext expand slice arguments and get in the transaction
+func (tx *Tx) GetInContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	query, args, err := tx.In(query, args...)
+	if err != nil {
+		return err
+	}
+	return tx.GetContext(ctx, dest, query, args...)
+}
+
+// ExecIn expand slice arguments and exec in the transaction
+func (tx *Tx) ExecIn(query string, args ...interface{}) (sql.Result, error) {
+	return tx.ExecInContext(context.Background(), query, args...)
+}
+
+// ExecInContext expand slice arguments and exec in the transaction
+func (tx *Tx) ExecInContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query, args, err := tx.In(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return tx.ExecContext(ctx, query, args...)
+}
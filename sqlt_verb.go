@@ -0,0 +1,53 @@
+package sqlt
+
+import (
+	"regexp"
+	"strings"
+)
+
+// WithVerbRouting route every query by inspecting its SQL instead of the method used, plain SELECT
+// go to slaves and everything else go to master. Use this when migrating from *sqlx.DB without
+// auditing every call site. Methods explicitly using master are always routed to master
+func WithVerbRouting() Option {
+	return func(o *options) {
+		o.verbRouting = true
+		o.pipeline = true
+	}
+}
+
+var (
+	leadingCommentRegexp = regexp.MustCompile(`^(\s|\(|--[^\n]*\n?|/\*(.|\n)*?\*/)+`)
+	readVerbRegexp       = regexp.MustCompile(`(?i)^(select|show|explain|describe|desc|values|with)\b`)
+	// locking reads, SELECT INTO, sequence changes and data modifying CTE must go to master
+	writeHintRegexp = regexp.MustCompile(`(?i)\b(for\s+(no\s+key\s+)?update|for\s+(key\s+)?share|lock\s+in\s+share\s+mode|into|nextval|setval|insert|update|delete|merge|upsert|replace)\b`)
+)
+
+// isReadQuery report whether the query is a plain read which can be served by a slave
+func isReadQuery(query string) bool {
+	query = leadingCommentRegexp.ReplaceAllString(query, "")
+	verb := readVerbRegexp.FindString(query)
+	if verb == "" {
+		return false
+	}
+	// EXPLAIN ANALYZE execute the statement
+	if strings.EqualFold(verb, "explain") && !isReadQuery(explained(query)) {
+		return false
+	}
+	return !writeHintRegexp.MatchString(query)
+}
+
+// explained return the statement of EXPLAIN, skipping its options
+func explained(query string) string {
+	query = strings.TrimSpace(query[len("explain"):])
+	if strings.HasPrefix(query, "(") {
+		if i := strings.Index(query, ")"); i >= 0 {
+			query = query[i+1:]
+		}
+	}
+	for _, opt := range []string{"analyze", "verbose"} {
+		if len(query) >= len(opt) && strings.EqualFold(query[:len(opt)], opt) {
+			query = strings.TrimSpace(query[len(opt):])
+		}
+	}
+	return query
+}
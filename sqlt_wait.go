@@ -0,0 +1,52 @@
+package sqlt
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// waitHealthyInterval is the longest wait between the checks of WaitForHealthy
+const waitHealthyInterval = time.Millisecond * 200
+
+// WaitForHealthy block until the master and at least minReplicas slaves are reachable, the
+// disconnected nodes are pinged on every check so the heartbeat doesn't need to be running.
+// When ctx is done the context error is returned joined with the last health error
+func (db *DB) WaitForHealthy(ctx context.Context, minReplicas int) error {
+	interval := db.beatInterval()
+	if interval > waitHealthyInterval {
+		interval = waitHealthyInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if down := db.disconnected(); len(down) > 0 {
+			// the ping errors are reported by checkHealth
+			db.ping(ctx, down)
+		}
+		err := db.checkHealth(minReplicas)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), err)
+		}
+	}
+}
+
+// disconnected return the members which are not connected
+func (db *DB) disconnected() []*node {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var down []*node
+	for _, n := range db.members() {
+		if !n.status.Connected {
+			down = append(down, n)
+		}
+	}
+	return down
+}
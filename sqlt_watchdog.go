@@ -0,0 +1,162 @@
+package sqlt
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLongRunning is the number of finished long-running queries kept by the watchdog
+const maxLongRunning = 64
+
+// Watchdog track the queries running longer than Threshold
+type Watchdog struct {
+	Threshold time.Duration
+	// Cancel the context of the query once it exceed the threshold
+	Cancel bool
+	// OnLongRunning is called once for every query exceeding the threshold, while it is still running
+	OnLongRunning func(q LongRunningQuery)
+}
+
+// LongRunningQuery is a query which exceeded the watchdog threshold
+type LongRunningQuery struct {
+	Op    string `json:"op"`
+	Query string `json:"query"`
+	Node  string `json:"node"`
+	// Caller is the first function outside of sqlt running the query, as "file:line"
+	Caller   string        `json:"caller"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	Running  bool          `json:"running"`
+	Canceled bool          `json:"canceled"`
+}
+
+// WithWatchdog track the queries running longer than the watchdog threshold,
+// see LongRunningQueries
+func WithWatchdog(w Watchdog) Option {
+	return func(o *options) {
+		if w.Threshold > 0 {
+			o.watchdog = &w
+			o.pipeline = true
+		}
+	}
+}
+
+// watchdog hold the running and the last finished long-running queries
+type watchdog struct {
+	Watchdog
+	mu       sync.Mutex
+	running  map[*LongRunningQuery]struct{}
+	finished []LongRunningQuery
+}
+
+func newWatchdog(w *Watchdog) *watchdog {
+	if w == nil {
+		return nil
+	}
+	return &watchdog{Watchdog: *w, running: make(map[*LongRunningQuery]struct{})}
+}
+
+// watch start watching the query, unwatch must be called once the query return and cancel once
+// its context is not used anymore, e.g. after the rows of a streamed query are closed.
+// The returned context is canceled when the query exceed the threshold and Cancel is set
+func (w *watchdog) watch(ctx context.Context, c call, n *node) (context.Context, func(), context.CancelFunc) {
+	if w == nil {
+		return ctx, func() {}, func() {}
+	}
+
+	var cancel context.CancelFunc
+	if w.Cancel {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	var pcs [16]uintptr
+	depth := runtime.Callers(3, pcs[:])
+	q := &LongRunningQuery{Op: c.op, Query: c.query, Node: n.name, Started: time.Now()}
+	// done is set under mu once the query return, the timer may fire right before
+	done := false
+
+	timer := time.AfterFunc(w.Threshold, func() {
+		w.mu.Lock()
+		if done {
+			w.mu.Unlock()
+			return
+		}
+		q.Caller = caller(pcs[:depth])
+		q.Running = true
+		q.Canceled = w.Cancel
+		w.running[q] = struct{}{}
+		report := *q
+		w.mu.Unlock()
+
+		report.Duration = time.Since(q.Started)
+		if w.OnLongRunning != nil {
+			w.OnLongRunning(report)
+		}
+		if cancel != nil {
+			cancel()
+		}
+	})
+
+	unwatch := func() {
+		timer.Stop()
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		done = true
+		if _, ok := w.running[q]; !ok {
+			return
+		}
+		delete(w.running, q)
+		q.Running = false
+		q.Duration = time.Since(q.Started)
+		if len(w.finished) == maxLongRunning {
+			w.finished = w.finished[1:]
+		}
+		w.finished = append(w.finished, *q)
+	}
+	if cancel == nil {
+		return ctx, unwatch, func() {}
+	}
+	return ctx, unwatch, cancel
+}
+
+// caller return the first frame outside of sqlt and sqlx
+func caller(pcs []uintptr) string {
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.Function, "/sqlt.") && !strings.Contains(frame.Function, "/sqlt/v2.") &&
+			!strings.Contains(frame.Function, "/sqlx.") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// LongRunningQueries return the queries currently running longer than the watchdog threshold,
+// followed by the last finished ones. Nil when the watchdog is not enabled
+func (db *DB) LongRunningQueries() []LongRunningQuery {
+	w := db.watchdog
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	queries := make([]LongRunningQuery, 0, len(w.running)+len(w.finished))
+	for q := range w.running {
+		running := *q
+		running.Duration = time.Since(q.Started)
+		queries = append(queries, running)
+	}
+	sort.Slice(queries, func(i, j int) bool {
+		return queries[i].Started.Before(queries[j].Started)
+	})
+	return append(queries, w.finished...)
+}
@@ -0,0 +1,129 @@
+// Package sqlt is the context-first API of sqlt. Every method take a context, routing is
+// chosen with query options instead of doubled method names and results are typed with generics.
+//
+// The package wrap the v1 DB, so existing callers can migrate one call at a time:
+//
+//	db := sqlt.Wrap(legacy)
+//	user, err := sqlt.Get[User](ctx, db, sqlt.Q("SELECT * FROM users WHERE id = $1", id), sqlt.Master())
+//	legacy = db.V1()
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+
+	v1 "github.com/albert-widi/sqlt"
+	"github.com/jmoiron/sqlx"
+)
+
+// Option configure the DB when opening the connection
+type Option = v1.Option
+
+// DB is a group of master and slaves database
+type DB struct {
+	db *v1.DB
+}
+
+// Open connection to database, sources is separated by ";" and the first source is the master
+func Open(ctx context.Context, driverName, sources string, opts ...Option) (*DB, error) {
+	db, err := v1.OpenWithContext(ctx, driverName, sources, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return Wrap(db), nil
+}
+
+// Wrap the v1 DB, both DB share the same connections
+func Wrap(db *v1.DB) *DB {
+	return &DB{db: db}
+}
+
+// V1 return the v1 DB sharing the same connections
+func (db *DB) V1() *v1.DB {
+	return db.db
+}
+
+// Query is a query and its arguments
+type Query struct {
+	SQL  string
+	Args []interface{}
+}
+
+// Q return the query with its arguments
+func Q(query string, args ...interface{}) Query {
+	return Query{SQL: query, Args: args}
+}
+
+type queryOptions struct {
+	master bool
+}
+
+// QueryOption configure how a single query is routed
+type QueryOption func(*queryOptions)
+
+// Master route the read to master, e.g. to read your own writes
+func Master() QueryOption {
+	return func(o *queryOptions) {
+		o.master = true
+	}
+}
+
+func newQueryOptions(opts []QueryOption) queryOptions {
+	var o queryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Get a single row into T, using slave unless routed to master
+func Get[T any](ctx context.Context, db *DB, q Query, opts ...QueryOption) (T, error) {
+	var dest T
+	var err error
+	if newQueryOptions(opts).master {
+		err = db.db.GetMasterContext(ctx, &dest, q.SQL, q.Args...)
+	} else {
+		err = db.db.GetContext(ctx, &dest, q.SQL, q.Args...)
+	}
+	return dest, err
+}
+
+// Select rows into a slice of T, using slave unless routed to master
+func Select[T any](ctx context.Context, db *DB, q Query, opts ...QueryOption) ([]T, error) {
+	var dest []T
+	var err error
+	if newQueryOptions(opts).master {
+		err = db.db.SelectMasterContext(ctx, &dest, q.SQL, q.Args...)
+	} else {
+		err = db.db.SelectContext(ctx, &dest, q.SQL, q.Args...)
+	}
+	return dest, err
+}
+
+// Query the rows, using slave unless routed to master
+func (db *DB) Query(ctx context.Context, q Query, opts ...QueryOption) (*sqlx.Rows, error) {
+	if newQueryOptions(opts).master {
+		return db.db.QueryxMasterContext(ctx, q.SQL, q.Args...)
+	}
+	return db.db.QueryxContext(ctx, q.SQL, q.Args...)
+}
+
+// Exec the query using master
+func (db *DB) Exec(ctx context.Context, q Query) (sql.Result, error) {
+	return db.db.ExecContext(ctx, q.SQL, q.Args...)
+}
+
+// InTx run fn inside a master transaction, see v1 DB.InTx
+func (db *DB) InTx(ctx context.Context, opts *sql.TxOptions, fn func(*sqlx.Tx) error) error {
+	return db.db.InTx(ctx, opts, fn)
+}
+
+// Ping every node
+func (db *DB) Ping(ctx context.Context) error {
+	return db.db.PingContext(ctx)
+}
+
+// Close every node
+func (db *DB) Close() error {
+	return db.db.Close()
+}
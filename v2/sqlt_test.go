@@ -0,0 +1,106 @@
+package sqlt
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	v1 "github.com/albert-widi/sqlt"
+)
+
+// mockDriver answer every query with a single row holding the DSN of the connection
+type mockDriver struct{}
+
+type (
+	mockConn struct{ dsn string }
+	mockRows struct {
+		value string
+		done  bool
+	}
+)
+
+func init() {
+	sql.Register("sqltv2mock", mockDriver{})
+}
+
+func (mockDriver) Open(dsn string) (driver.Conn, error) { return &mockConn{dsn: dsn}, nil }
+
+func (*mockConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (*mockConn) Close() error                        { return nil }
+func (c *mockConn) Begin() (driver.Tx, error)         { return nil, driver.ErrSkip }
+
+func (c *mockConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &mockRows{value: c.dsn}, nil
+}
+
+func (*mockRows) Columns() []string { return []string{"dsn"} }
+func (*mockRows) Close() error      { return nil }
+
+func (r *mockRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+// newMockDB return a DB which master and slave answer with their name
+func newMockDB(t *testing.T) *DB {
+	t.Helper()
+	var conns []*sql.DB
+	for _, dsn := range []string{"master", "slave-1"} {
+		conn, err := sql.Open("sqltv2mock", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, conn)
+	}
+	db := Wrap(v1.InitMockingWithConns(conns[0], conns[1:]...))
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRouting(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []QueryOption
+		want string
+	}{
+		{name: "slave", want: "slave-1"},
+		{name: "master", opts: []QueryOption{Master()}, want: "master"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDB(t)
+			ctx := context.Background()
+			q := Q("SELECT dsn")
+
+			got, err := Get[string](ctx, db, q, tt.opts...)
+			if err != nil || got != tt.want {
+				t.Fatalf("Get: %q, %v, want %q", got, err, tt.want)
+			}
+			all, err := Select[string](ctx, db, q, tt.opts...)
+			if err != nil || len(all) != 1 || all[0] != tt.want {
+				t.Fatalf("Select: %q, %v, want %q", all, err, tt.want)
+			}
+
+			// the rows are routed by the v1 pipeline, which record the node serving them
+			metaCtx, meta := v1.WithResultMetadata(ctx)
+			rows, err := db.Query(metaCtx, q, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			var dsn string
+			if !rows.Next() || rows.Scan(&dsn) != nil || dsn != tt.want {
+				t.Fatalf("Query: %q, want %q", dsn, tt.want)
+			}
+			if meta.Node != tt.want {
+				t.Fatalf("Query served by %q, want %q", meta.Node, tt.want)
+			}
+		})
+	}
+}